@@ -0,0 +1,558 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Alert rule kinds
+const (
+	AlertRuleEvent = "event" // Matches change events as they are detected
+	AlertRuleState = "state" // Matches the current state after every refresh
+)
+
+// Alert severities
+const (
+	AlertSeverityInfo     = "info"
+	AlertSeverityWarning  = "warning"
+	AlertSeverityCritical = "critical"
+)
+
+const (
+	// MaxAlerts is the number of alerts kept, the oldest are dropped first
+	MaxAlerts = 1000
+
+	// DefaultSilenceDuration is how long the silence key mutes an alert
+	DefaultSilenceDuration = time.Hour
+)
+
+// ruleDuration is a duration written as a string such as "2m" in rules files
+type ruleDuration time.Duration
+
+// UnmarshalJSON parses a duration string
+func (d *ruleDuration) UnmarshalJSON(data []byte) error {
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return fmt.Errorf("duration must be a string such as \"2m\": %s", data)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = ruleDuration(parsed)
+	return nil
+}
+
+// AlertRule describes when an alert is raised. Empty criteria match anything;
+// strings are compared case-insensitively.
+type AlertRule struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`     // event (default) or state
+	Severity string `json:"severity"` // info, warning (default) or critical
+	Message  string `json:"message"`  // Replaces the generated alert message
+
+	ResourceType string `json:"resourceType"` // Node, Pod or Container
+	ResourceName string `json:"resourceName"` // Glob matched against e.g. node/pod, see path.Match
+	Namespace    string `json:"namespace"`
+	Field        string `json:"field"` // e.g. Status, RestartCount, MemoryPressure
+	Value        string `json:"value"` // The new value of events, or the current value
+
+	// Event rules
+	ChangeType string `json:"changeType"` // Added, Removed or Modified
+	Increase   bool   `json:"increase"`   // Only numeric values that went up
+
+	// State rules
+	For       ruleDuration `json:"for"`       // How long the state must hold before alerting
+	Threshold *int         `json:"threshold"` // Alert once when more than this many resources match
+}
+
+// alertRulesFile is the layout of a rules file
+type alertRulesFile struct {
+	Rules []AlertRule `json:"rules"`
+}
+
+// LoadAlertRules reads and validates a YAML or JSON rules file
+func LoadAlertRules(filename string) ([]AlertRule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var file alertRulesFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	names := make(map[string]bool)
+	for i := range file.Rules {
+		rule := &file.Rules[i]
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %v", filename, i+1, err)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("%s: duplicate rule name %q", filename, rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return file.Rules, nil
+}
+
+// validate checks a rule and fills in defaults
+func (r *AlertRule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	if r.Kind == "" {
+		r.Kind = AlertRuleEvent
+	}
+	if r.Severity == "" {
+		r.Severity = AlertSeverityWarning
+	}
+	switch r.Severity {
+	case AlertSeverityInfo, AlertSeverityWarning, AlertSeverityCritical:
+	default:
+		return fmt.Errorf("%s: invalid severity %q", r.Name, r.Severity)
+	}
+	if r.ResourceName != "" {
+		if _, err := path.Match(r.ResourceName, ""); err != nil {
+			return fmt.Errorf("%s: invalid resourceName pattern: %v", r.Name, err)
+		}
+	}
+
+	switch r.Kind {
+	case AlertRuleEvent:
+		if r.For != 0 || r.Threshold != nil {
+			return fmt.Errorf("%s: for and threshold only apply to state rules", r.Name)
+		}
+	case AlertRuleState:
+		if r.ResourceType == "" || r.Field == "" {
+			return fmt.Errorf("%s: state rules need a resourceType and field", r.Name)
+		}
+		if r.ChangeType != "" || r.Increase {
+			return fmt.Errorf("%s: changeType and increase only apply to event rules", r.Name)
+		}
+		if r.Threshold != nil && *r.Threshold < 0 {
+			return fmt.Errorf("%s: threshold must not be negative", r.Name)
+		}
+	default:
+		return fmt.Errorf("%s: invalid kind %q, expected event or state", r.Name, r.Kind)
+	}
+	return nil
+}
+
+// matchesResource reports whether a resource passes the rule's resource criteria
+func (r *AlertRule) matchesResource(resourceType, name, namespace string) bool {
+	if r.ResourceType != "" && !strings.EqualFold(r.ResourceType, resourceType) {
+		return false
+	}
+	if r.Namespace != "" && !strings.EqualFold(r.Namespace, namespace) {
+		return false
+	}
+	if r.ResourceName != "" {
+		if matched, _ := path.Match(r.ResourceName, name); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesChange reports whether an event rule matches a change event
+func (r *AlertRule) MatchesChange(change ChangeEvent) bool {
+	if r.Kind != AlertRuleEvent || !r.matchesResource(change.ResourceType, change.ResourceName, change.Namespace) {
+		return false
+	}
+	if r.ChangeType != "" && !strings.EqualFold(r.ChangeType, change.ChangeType) {
+		return false
+	}
+	if r.Field != "" && !strings.EqualFold(r.Field, change.Field) {
+		return false
+	}
+	if r.Value != "" && !strings.EqualFold(r.Value, formatValue(change.NewValue)) {
+		return false
+	}
+	if r.Increase {
+		oldValue, errOld := strconv.ParseFloat(formatValue(change.OldValue), 64)
+		newValue, errNew := strconv.ParseFloat(formatValue(change.NewValue), 64)
+		if errOld != nil || errNew != nil || newValue <= oldValue {
+			return false
+		}
+	}
+	return true
+}
+
+// Alert is raised when a rule matches. Event alerts stay until acknowledged,
+// state alerts resolve when the state no longer holds.
+type Alert struct {
+	ID            int
+	Rule          string
+	Severity      string
+	ResourceType  string
+	ResourceName  string // Empty for threshold alerts
+	Namespace     string
+	Message       string
+	FirstSeen     time.Time
+	LastSeen      time.Time
+	Count         int // Matching events, or refreshes the state held
+	Resolved      bool
+	Acknowledged  bool
+	SilencedUntil time.Time // Set on copies returned by Alerts while silenced
+}
+
+// key identifies the rule and resource an alert is about
+func (a *Alert) key() string {
+	return a.Rule + "\x00" + a.ResourceName
+}
+
+// stateResource is a node, pod or container with its current field values
+type stateResource struct {
+	resourceType string
+	name         string
+	namespace    string
+	fields       map[string]string
+}
+
+// field returns the value of a field, matching its name case-insensitively
+func (r stateResource) field(name string) (string, bool) {
+	if value, ok := r.fields[name]; ok {
+		return value, true
+	}
+	for field, value := range r.fields {
+		if strings.EqualFold(field, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// stateResources lists the nodes, pods and containers of the cluster with the
+// fields state rules can match
+func stateResources(nodeData map[string]NodeData) []stateResource {
+	var resources []stateResource
+	for nodeName, node := range nodeData {
+		fields := map[string]string{
+			"Status":        node.Status,
+			"Version":       node.Version,
+			"Unschedulable": strconv.FormatBool(node.Unschedulable),
+		}
+		for condition, status := range node.Conditions {
+			fields[condition] = status
+		}
+		resources = append(resources, stateResource{resourceType: "Node", name: nodeName, fields: fields})
+
+		for podName, pod := range node.Pods {
+			podKey := nodeName + "/" + podName
+			resources = append(resources, stateResource{
+				resourceType: "Pod",
+				name:         podKey,
+				namespace:    pod.Namespace,
+				fields: map[string]string{
+					"Status":       pod.Status,
+					"RestartCount": strconv.Itoa(pod.RestartCount),
+				},
+			})
+			for containerName, container := range pod.ContainerInfo {
+				resources = append(resources, stateResource{
+					resourceType: "Container",
+					name:         podKey + "/" + containerName,
+					namespace:    pod.Namespace,
+					fields: map[string]string{
+						"Status":       container.Status,
+						"RestartCount": strconv.Itoa(container.RestartCount),
+					},
+				})
+			}
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].name < resources[j].name })
+	return resources
+}
+
+// AlertEngine evaluates alert rules against change events and cluster state
+type AlertEngine struct {
+	mu        sync.Mutex
+	rules     []AlertRule
+	alerts    []*Alert // Oldest first
+	nextID    int
+	pending   map[string]time.Time // Since when the state of a rule and resource holds
+	silences  map[string]time.Time // Alert keys muted until the given time
+	alertFunc func(Alert)
+	fired     []Alert          // New alerts not yet passed to alertFunc
+	now       func() time.Time // Clock of silences, replaced in tests
+}
+
+// NewAlertEngine creates an engine for the given rules
+func NewAlertEngine(rules []AlertRule) *AlertEngine {
+	return &AlertEngine{
+		rules:    rules,
+		nextID:   1,
+		pending:  make(map[string]time.Time),
+		silences: make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+// Rules returns the rules of the engine
+func (e *AlertEngine) Rules() []AlertRule {
+	return e.rules
+}
+
+// SetAlertFunc sets the handler called for every new alert that isn't
+// silenced. It's called after the engine is unlocked, so it may block.
+func (e *AlertEngine) SetAlertFunc(handler func(Alert)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.alertFunc = handler
+}
+
+// ProcessChange raises an alert for every event rule matching the change
+func (e *AlertEngine) ProcessChange(change ChangeEvent) {
+	defer e.notifyFired()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i := range e.rules {
+		rule := &e.rules[i]
+		if !rule.MatchesChange(change) {
+			continue
+		}
+		message := rule.Message
+		if message == "" {
+			message = fmt.Sprintf("%s %s %s", change.ResourceType, change.ResourceName, change.ChangeType)
+			if change.Field != "" {
+				message = fmt.Sprintf("%s %s %s → %s", change.ResourceType, change.ResourceName, change.Field,
+					formatValue(change.NewValue))
+				if change.OldValue != nil {
+					message = fmt.Sprintf("%s %s %s %s → %s", change.ResourceType, change.ResourceName, change.Field,
+						formatValue(change.OldValue), formatValue(change.NewValue))
+				}
+			}
+		}
+		e.fire(rule, change.ResourceType, change.ResourceName, change.Namespace, message, change.Timestamp)
+	}
+}
+
+// EvaluateState checks the state rules against the cluster state, raising
+// alerts for states that held long enough and resolving those that ended
+func (e *AlertEngine) EvaluateState(nodeData map[string]NodeData, now time.Time) {
+	resources := stateResources(nodeData)
+
+	defer e.notifyFired()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i := range e.rules {
+		rule := &e.rules[i]
+		if rule.Kind != AlertRuleState {
+			continue
+		}
+
+		var matching []stateResource
+		for _, resource := range resources {
+			value, ok := resource.field(rule.Field)
+			if ok && rule.matchesResource(resource.resourceType, resource.name, resource.namespace) &&
+				(rule.Value == "" || strings.EqualFold(rule.Value, value)) {
+				matching = append(matching, resource)
+			}
+		}
+
+		if rule.Threshold != nil {
+			key := rule.Name + "\x00"
+			if len(matching) <= *rule.Threshold {
+				delete(e.pending, key)
+				e.resolve(key, now)
+				continue
+			}
+			if e.held(key, time.Duration(rule.For), now) {
+				message := rule.Message
+				if message == "" {
+					message = fmt.Sprintf("%d %ss match %s (more than %d)", len(matching), rule.ResourceType,
+						rule.Name, *rule.Threshold)
+					if rule.Value != "" {
+						message = fmt.Sprintf("%d %ss with %s %s (more than %d)", len(matching), rule.ResourceType,
+							rule.Field, rule.Value, *rule.Threshold)
+					}
+				}
+				e.fire(rule, rule.ResourceType, "", rule.Namespace, message, now)
+			}
+			continue
+		}
+
+		current := make(map[string]bool, len(matching))
+		for _, resource := range matching {
+			key := rule.Name + "\x00" + resource.name
+			current[key] = true
+			if !e.held(key, time.Duration(rule.For), now) {
+				continue
+			}
+			message := rule.Message
+			if message == "" {
+				value, _ := resource.field(rule.Field)
+				message = fmt.Sprintf("%s %s %s is %s", resource.resourceType, resource.name, rule.Field, value)
+				if rule.For > 0 {
+					message += " for " + formatFlapDuration(now.Sub(e.pending[key]))
+				}
+			}
+			e.fire(rule, resource.resourceType, resource.name, resource.namespace, message, now)
+		}
+
+		// Resolve the resources of this rule whose state ended
+		for key := range e.pending {
+			if strings.HasPrefix(key, rule.Name+"\x00") && !current[key] {
+				delete(e.pending, key)
+				e.resolve(key, now)
+			}
+		}
+	}
+}
+
+// held records since when a state holds and reports whether it held for at least d
+func (e *AlertEngine) held(key string, d time.Duration, now time.Time) bool {
+	since, ok := e.pending[key]
+	if !ok {
+		since = now
+		e.pending[key] = since
+	}
+	return now.Sub(since) >= d
+}
+
+// fire raises an alert or updates the open alert for the same rule and
+// resource. Acknowledged event alerts are not updated, a new alert is raised
+// instead. Must be called with the lock held.
+func (e *AlertEngine) fire(rule *AlertRule, resourceType, resourceName, namespace, message string, now time.Time) {
+	key := rule.Name + "\x00" + resourceName
+	if until, ok := e.silences[key]; ok {
+		if now.Before(until) {
+			return
+		}
+		delete(e.silences, key)
+	}
+
+	for i := len(e.alerts) - 1; i >= 0; i-- {
+		alert := e.alerts[i]
+		if alert.key() != key || alert.Resolved || (alert.Acknowledged && rule.Kind == AlertRuleEvent) {
+			continue
+		}
+		alert.LastSeen = now
+		alert.Count++
+		alert.Message = message
+		return
+	}
+
+	alert := &Alert{
+		ID:           e.nextID,
+		Rule:         rule.Name,
+		Severity:     rule.Severity,
+		ResourceType: resourceType,
+		ResourceName: resourceName,
+		Namespace:    namespace,
+		Message:      message,
+		FirstSeen:    now,
+		LastSeen:     now,
+		Count:        1,
+	}
+	e.nextID++
+	e.alerts = append(e.alerts, alert)
+	if len(e.alerts) > MaxAlerts {
+		e.alerts = e.alerts[len(e.alerts)-MaxAlerts:]
+	}
+	e.fired = append(e.fired, *alert)
+}
+
+// notifyFired passes the alerts fired since the last call to the alert func.
+// It must be called without the lock held.
+func (e *AlertEngine) notifyFired() {
+	e.mu.Lock()
+	fired, handler := e.fired, e.alertFunc
+	e.fired = nil
+	e.mu.Unlock()
+	if handler == nil {
+		return
+	}
+	for _, alert := range fired {
+		handler(alert)
+	}
+}
+
+// resolve marks the open alert with the given key as resolved. Must be called
+// with the lock held.
+func (e *AlertEngine) resolve(key string, now time.Time) {
+	for _, alert := range e.alerts {
+		if alert.key() == key && !alert.Resolved {
+			alert.Resolved = true
+			alert.LastSeen = now
+		}
+	}
+}
+
+// Alerts returns copies of the alerts, newest first
+func (e *AlertEngine) Alerts() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.now()
+	alerts := make([]Alert, 0, len(e.alerts))
+	for i := len(e.alerts) - 1; i >= 0; i-- {
+		alert := *e.alerts[i]
+		if until, ok := e.silences[alert.key()]; ok && now.Before(until) {
+			alert.SilencedUntil = until
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+// ToggleAcknowledged acknowledges an alert, or takes the acknowledgement back
+func (e *AlertEngine) ToggleAcknowledged(id int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if alert := e.find(id); alert != nil {
+		alert.Acknowledged = !alert.Acknowledged
+	}
+}
+
+// ToggleSilenced mutes new alerts for the rule and resource of an alert for d,
+// or lifts an active silence
+func (e *AlertEngine) ToggleSilenced(id int, d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	alert := e.find(id)
+	if alert == nil {
+		return
+	}
+	now := e.now()
+	if until, ok := e.silences[alert.key()]; ok && now.Before(until) {
+		delete(e.silences, alert.key())
+		return
+	}
+	e.silences[alert.key()] = now.Add(d)
+}
+
+// find returns the alert with the given ID. Must be called with the lock held.
+func (e *AlertEngine) find(id int) *Alert {
+	for _, alert := range e.alerts {
+		if alert.ID == id {
+			return alert
+		}
+	}
+	return nil
+}
+
+// ActiveCounts returns the number of unresolved alerts and of those not acknowledged
+func (e *AlertEngine) ActiveCounts() (active, unacknowledged int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, alert := range e.alerts {
+		if alert.Resolved {
+			continue
+		}
+		active++
+		if !alert.Acknowledged {
+			unacknowledged++
+		}
+	}
+	return active, unacknowledged
+}
@@ -0,0 +1,397 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exampleRules are the rules of the README example
+const exampleRules = `rules:
+  - name: payments-restarts
+    resourceType: Container
+    namespace: payments
+    field: RestartCount
+    increase: true
+  - name: node-not-ready
+    kind: state
+    severity: critical
+    resourceType: Node
+    field: Status
+    value: NotReady
+    for: 2m
+  - name: many-pending
+    kind: state
+    resourceType: Pod
+    field: Status
+    value: Pending
+    threshold: 5
+`
+
+// alertStart is the time the fake clock of the alert tests starts at
+var alertStart = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// alertSummary is the part of an alert compared by the tests
+type alertSummary struct {
+	ID           int
+	Rule         string
+	ResourceName string
+	Message      string
+	Count        int
+	Resolved     bool
+	Acknowledged bool
+}
+
+// summarizeAlerts drops the timestamps and severity of alerts
+func summarizeAlerts(alerts []Alert) []alertSummary {
+	var summaries []alertSummary
+	for _, alert := range alerts {
+		summaries = append(summaries, alertSummary{
+			alert.ID, alert.Rule, alert.ResourceName, alert.Message, alert.Count, alert.Resolved, alert.Acknowledged,
+		})
+	}
+	return summaries
+}
+
+// loadRules parses a rules file written to a temporary directory
+func loadRules(t *testing.T, content string) ([]AlertRule, error) {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadAlertRules(filename)
+}
+
+// exampleEngine returns an engine with the README rules and a fake clock
+// that the returned function moves to alertStart plus an offset
+func exampleEngine(t *testing.T) (*AlertEngine, func(time.Duration) time.Time) {
+	t.Helper()
+	rules, err := loadRules(t, exampleRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewAlertEngine(rules)
+	now := alertStart
+	engine.now = func() time.Time { return now }
+	return engine, func(offset time.Duration) time.Time {
+		now = alertStart.Add(offset)
+		return now
+	}
+}
+
+// clusterState returns the state of one node with pods of the given statuses
+// in namespace shop
+func clusterState(nodeStatus string, podStatuses ...string) map[string]NodeData {
+	node := NodeData{Name: "node-1", Status: nodeStatus, Pods: make(map[string]PodInfo)}
+	for i, status := range podStatuses {
+		name := "web-" + string(rune('a'+i))
+		node.Pods[name] = PodInfo{Name: name, Namespace: "shop", Status: status}
+	}
+	return map[string]NodeData{"node-1": node}
+}
+
+// restart returns a container restart change in a namespace
+func restart(namespace string, old, new int) ChangeEvent {
+	return ChangeEvent{
+		ResourceType: "Container",
+		ResourceName: "node-1/api-0/app",
+		Namespace:    namespace,
+		ChangeType:   "Modified",
+		Field:        "RestartCount",
+		OldValue:     old,
+		NewValue:     new,
+	}
+}
+
+func TestLoadAlertRules(t *testing.T) {
+	threshold := 5
+	rules, err := loadRules(t, exampleRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []AlertRule{
+		{Name: "payments-restarts", Kind: AlertRuleEvent, Severity: AlertSeverityWarning, ResourceType: "Container",
+			Namespace: "payments", Field: "RestartCount", Increase: true},
+		{Name: "node-not-ready", Kind: AlertRuleState, Severity: AlertSeverityCritical, ResourceType: "Node",
+			Field: "Status", Value: "NotReady", For: ruleDuration(2 * time.Minute)},
+		{Name: "many-pending", Kind: AlertRuleState, Severity: AlertSeverityWarning, ResourceType: "Pod",
+			Field: "Status", Value: "Pending", Threshold: &threshold},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("got %+v, want %+v", rules, want)
+	}
+}
+
+func TestLoadAlertRulesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"missing name", "rules:\n  - field: Status\n", "missing name"},
+		{"duplicate name", "rules:\n  - name: a\n  - name: a\n", `duplicate rule name "a"`},
+		{"unknown field", "rules:\n  - name: a\n    feild: Status\n", "feild"},
+		{"invalid kind", "rules:\n  - name: a\n    kind: sometimes\n", "invalid kind"},
+		{"invalid severity", "rules:\n  - name: a\n    severity: fatal\n", "invalid severity"},
+		{"invalid duration", "rules:\n  - name: a\n    kind: state\n    resourceType: Node\n    field: Status\n    for: soon\n", "soon"},
+		{"numeric duration", "rules:\n  - name: a\n    kind: state\n    resourceType: Node\n    field: Status\n    for: 120\n", "must be a string"},
+		{"invalid glob", "rules:\n  - name: a\n    resourceName: \"[\"\n", "invalid resourceName"},
+		{"event rule with for", "rules:\n  - name: a\n    for: 2m\n", "only apply to state rules"},
+		{"state rule without field", "rules:\n  - name: a\n    kind: state\n    resourceType: Node\n", "need a resourceType and field"},
+		{"state rule with increase", "rules:\n  - name: a\n    kind: state\n    resourceType: Pod\n    field: RestartCount\n    increase: true\n", "only apply to event rules"},
+		{"negative threshold", "rules:\n  - name: a\n    kind: state\n    resourceType: Pod\n    field: Status\n    threshold: -1\n", "must not be negative"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := loadRules(t, test.content)
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("got error %v, want one containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestAlertRuleMatchesChange(t *testing.T) {
+	rules, err := loadRules(t, exampleRules+`  - name: node-removed
+    resourceType: Node
+    changeType: Removed
+  - name: web-crashloop
+    resourceType: Pod
+    resourceName: "*/web-*"
+    field: Status
+    value: crashloopbackoff
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]*AlertRule)
+	for i := range rules {
+		byName[rules[i].Name] = &rules[i]
+	}
+
+	podStatus := func(name, status string) ChangeEvent {
+		return ChangeEvent{ResourceType: "Pod", ResourceName: name, Namespace: "shop", ChangeType: "Modified",
+			Field: "Status", OldValue: "Running", NewValue: status}
+	}
+	tests := []struct {
+		name   string
+		rule   string
+		change ChangeEvent
+		want   bool
+	}{
+		{"restart in payments", "payments-restarts", restart("payments", 1, 2), true},
+		{"restart in other namespace", "payments-restarts", restart("shop", 1, 2), false},
+		{"namespace case-insensitive", "payments-restarts", restart("Payments", 1, 2), true},
+		{"restart count reset", "payments-restarts", restart("payments", 2, 0), false},
+		{"first restart of added container", "payments-restarts", restart("payments", 0, 1), true},
+		{"pod restart", "payments-restarts", ChangeEvent{ResourceType: "Pod", Namespace: "payments", ChangeType: "Modified",
+			Field: "RestartCount", OldValue: 1, NewValue: 2}, false},
+		{"non-numeric values", "payments-restarts", ChangeEvent{ResourceType: "Container", Namespace: "payments",
+			ChangeType: "Added", Field: "RestartCount", NewValue: "Running"}, false},
+		{"change type", "node-removed", ChangeEvent{ResourceType: "Node", ResourceName: "node-1", ChangeType: "Removed"}, true},
+		{"other change type", "node-removed", ChangeEvent{ResourceType: "Node", ResourceName: "node-1", ChangeType: "Added"}, false},
+		{"glob and value", "web-crashloop", podStatus("node-1/web-1", "CrashLoopBackOff"), true},
+		{"glob mismatch", "web-crashloop", podStatus("node-1/api-1", "CrashLoopBackOff"), false},
+		{"value mismatch", "web-crashloop", podStatus("node-1/web-1", "Running"), false},
+		{"state rules never match changes", "node-not-ready", ChangeEvent{ResourceType: "Node", ResourceName: "node-1",
+			ChangeType: "Modified", Field: "Status", OldValue: NodeStatusReady, NewValue: "NotReady"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := byName[test.rule].MatchesChange(test.change); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// alertStep is one refresh or change in an alert engine test, followed by
+// the expected alerts, newest first
+type alertStep struct {
+	at     time.Duration // Since alertStart
+	state  map[string]NodeData
+	change *ChangeEvent
+	want   []alertSummary
+}
+
+func TestAlertEngine(t *testing.T) {
+	payments := restart("payments", 1, 2)
+	paymentsAgain := restart("payments", 2, 3)
+	tests := []struct {
+		name  string
+		steps []alertStep
+	}{
+		{
+			name: "container restarts in payments",
+			steps: []alertStep{
+				{change: &payments, want: []alertSummary{
+					{1, "payments-restarts", "node-1/api-0/app", "Container node-1/api-0/app RestartCount 1 → 2", 1, false, false},
+				}},
+				{at: time.Minute, change: &paymentsAgain, want: []alertSummary{
+					{1, "payments-restarts", "node-1/api-0/app", "Container node-1/api-0/app RestartCount 2 → 3", 2, false, false},
+				}},
+			},
+		},
+		{
+			name: "node NotReady for more than 2m",
+			steps: []alertStep{
+				{state: clusterState("NotReady")},
+				{at: time.Minute, state: clusterState("NotReady")},
+				{at: 2 * time.Minute, state: clusterState("NotReady"), want: []alertSummary{
+					{1, "node-not-ready", "node-1", "Node node-1 Status is NotReady for 2m", 1, false, false},
+				}},
+				{at: 3 * time.Minute, state: clusterState("NotReady"), want: []alertSummary{
+					{1, "node-not-ready", "node-1", "Node node-1 Status is NotReady for 3m", 2, false, false},
+				}},
+				{at: 4 * time.Minute, state: clusterState(NodeStatusReady), want: []alertSummary{
+					{1, "node-not-ready", "node-1", "Node node-1 Status is NotReady for 3m", 2, true, false},
+				}},
+			},
+		},
+		{
+			name: "node ready again before 2m",
+			steps: []alertStep{
+				{state: clusterState("NotReady")},
+				{at: time.Minute, state: clusterState(NodeStatusReady)},
+				// The time starts over
+				{at: 90 * time.Second, state: clusterState("NotReady")},
+				{at: 3 * time.Minute, state: clusterState("NotReady")},
+				{at: 4 * time.Minute, state: clusterState("NotReady"), want: []alertSummary{
+					{1, "node-not-ready", "node-1", "Node node-1 Status is NotReady for 2m", 1, false, false},
+				}},
+			},
+		},
+		{
+			name: "more than 5 pods Pending",
+			steps: []alertStep{
+				{state: clusterState(NodeStatusReady, "Pending", "Pending", "Pending", "Pending", "Pending", "Running")},
+				{at: time.Minute, state: clusterState(NodeStatusReady, "Pending", "Pending", "Pending", "Pending", "Pending", "Pending"), want: []alertSummary{
+					{1, "many-pending", "", "6 Pods with Status Pending (more than 5)", 1, false, false},
+				}},
+				{at: 2 * time.Minute, state: clusterState(NodeStatusReady, "pending", "Pending", "Pending", "Pending", "Pending", "Pending", "Pending"), want: []alertSummary{
+					{1, "many-pending", "", "7 Pods with Status Pending (more than 5)", 2, false, false},
+				}},
+				{at: 3 * time.Minute, state: clusterState(NodeStatusReady, "Pending", "Running"), want: []alertSummary{
+					{1, "many-pending", "", "7 Pods with Status Pending (more than 5)", 2, true, false},
+				}},
+				// A new alert once the threshold is crossed again
+				{at: 4 * time.Minute, state: clusterState(NodeStatusReady, "Pending", "Pending", "Pending", "Pending", "Pending", "Pending"), want: []alertSummary{
+					{2, "many-pending", "", "6 Pods with Status Pending (more than 5)", 1, false, false},
+					{1, "many-pending", "", "7 Pods with Status Pending (more than 5)", 2, true, false},
+				}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			engine, setTime := exampleEngine(t)
+			for i, step := range test.steps {
+				now := setTime(step.at)
+				if step.change != nil {
+					change := *step.change
+					change.Timestamp = now
+					engine.ProcessChange(change)
+				}
+				if step.state != nil {
+					engine.EvaluateState(step.state, now)
+				}
+				if got := summarizeAlerts(engine.Alerts()); !reflect.DeepEqual(got, step.want) {
+					t.Fatalf("step %d: got %+v, want %+v", i+1, got, step.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAlertEngineAcknowledge(t *testing.T) {
+	engine, setTime := exampleEngine(t)
+	change := restart("payments", 1, 2)
+	change.Timestamp = setTime(0)
+	engine.ProcessChange(change)
+	engine.EvaluateState(clusterState("NotReady"), setTime(0))
+	engine.EvaluateState(clusterState("NotReady"), setTime(2*time.Minute))
+	if active, unacknowledged := engine.ActiveCounts(); active != 2 || unacknowledged != 2 {
+		t.Fatalf("got %d active, %d unacknowledged, want 2, 2", active, unacknowledged)
+	}
+
+	engine.ToggleAcknowledged(1)
+	engine.ToggleAcknowledged(2)
+	if active, unacknowledged := engine.ActiveCounts(); active != 2 || unacknowledged != 0 {
+		t.Fatalf("got %d active, %d unacknowledged, want 2, 0", active, unacknowledged)
+	}
+
+	// An acknowledged event alert is cleared: the next match raises a new
+	// alert. An acknowledged state alert keeps counting until it resolves.
+	change = restart("payments", 2, 3)
+	change.Timestamp = setTime(3 * time.Minute)
+	engine.ProcessChange(change)
+	engine.EvaluateState(clusterState("NotReady"), setTime(3*time.Minute))
+	want := []alertSummary{
+		{3, "payments-restarts", "node-1/api-0/app", "Container node-1/api-0/app RestartCount 2 → 3", 1, false, false},
+		{2, "node-not-ready", "node-1", "Node node-1 Status is NotReady for 3m", 2, false, true},
+		{1, "payments-restarts", "node-1/api-0/app", "Container node-1/api-0/app RestartCount 1 → 2", 1, false, true},
+	}
+	if got := summarizeAlerts(engine.Alerts()); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// Taking the acknowledgement back
+	engine.ToggleAcknowledged(2)
+	if active, unacknowledged := engine.ActiveCounts(); active != 3 || unacknowledged != 2 {
+		t.Errorf("got %d active, %d unacknowledged, want 3, 2", active, unacknowledged)
+	}
+}
+
+func TestAlertEngineSilence(t *testing.T) {
+	engine, setTime := exampleEngine(t)
+	var notified []int
+	engine.SetAlertFunc(func(alert Alert) {
+		notified = append(notified, alert.ID)
+		engine.Alerts() // The handler may use the engine
+	})
+	process := func(at time.Duration, old, new int) {
+		change := restart("payments", old, new)
+		change.Timestamp = setTime(at)
+		engine.ProcessChange(change)
+	}
+
+	process(0, 1, 2)
+	engine.ToggleSilenced(1, 10*time.Minute)
+	silencedUntil := alertStart.Add(10 * time.Minute)
+	if alerts := engine.Alerts(); !alerts[0].SilencedUntil.Equal(silencedUntil) {
+		t.Fatalf("got SilencedUntil %v, want %v", alerts[0].SilencedUntil, silencedUntil)
+	}
+
+	// Matches are dropped while silenced
+	process(5*time.Minute, 2, 3)
+	if alerts := engine.Alerts(); alerts[0].Count != 1 {
+		t.Errorf("silenced alert was updated: %+v", alerts[0])
+	}
+
+	// The silence expires
+	setTime(11 * time.Minute)
+	if alerts := engine.Alerts(); !alerts[0].SilencedUntil.IsZero() {
+		t.Errorf("got SilencedUntil %v after expiry", alerts[0].SilencedUntil)
+	}
+	process(11*time.Minute, 3, 4)
+	if alerts := engine.Alerts(); alerts[0].Count != 2 {
+		t.Errorf("got count %d after the silence expired, want 2", alerts[0].Count)
+	}
+
+	// Toggling again lifts an active silence
+	engine.ToggleSilenced(1, time.Hour)
+	engine.ToggleSilenced(1, time.Hour)
+	process(12*time.Minute, 4, 5)
+	if alerts := engine.Alerts(); alerts[0].Count != 3 || !alerts[0].SilencedUntil.IsZero() {
+		t.Errorf("got %+v after lifting the silence, want count 3 and no silence", alerts[0])
+	}
+
+	// Only new alerts are passed to the alert func
+	if want := []int{1}; !reflect.DeepEqual(notified, want) {
+		t.Errorf("notified of %v, want %v", notified, want)
+	}
+}
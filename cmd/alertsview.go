@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// AlertsPaneHeight is the height of the alerts pane in the main view, including its border
+const AlertsPaneHeight = 8
+
+// alertsHeaders are the column titles of the alerts table
+var alertsHeaders = []string{"Time", "Severity", "Rule", "Resource", "Message", "Count", "State"}
+
+// AlertsView shows the alerts raised by the alert engine
+type AlertsView struct {
+	table  *tview.Table
+	engine *AlertEngine
+}
+
+// NewAlertsView creates a new AlertsView for the alerts of engine
+func NewAlertsView(engine *AlertEngine) *AlertsView {
+	table := tview.NewTable().
+		SetBorders(false).
+		SetFixed(1, 0).
+		SetSelectable(true, false).
+		SetSelectedStyle(tcell.StyleDefault.Background(tcell.ColorNavy))
+	table.SetBorder(true).
+		SetBorderColor(tcell.ColorGray).
+		SetBorderAttributes(tcell.AttrDim)
+
+	av := &AlertsView{
+		table:  table,
+		engine: engine,
+	}
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() != tcell.KeyRune {
+			return event
+		}
+		switch event.Rune() {
+		case KeyAlertAcknowledge:
+			if id, ok := av.selectedID(); ok {
+				av.engine.ToggleAcknowledged(id)
+				av.Refresh()
+			}
+			return nil
+		case KeyAlertSilence:
+			if id, ok := av.selectedID(); ok {
+				av.engine.ToggleSilenced(id, DefaultSilenceDuration)
+				av.Refresh()
+			}
+			return nil
+		}
+		return event
+	})
+
+	av.Refresh()
+	return av
+}
+
+// GetTable returns the underlying table
+func (av *AlertsView) GetTable() *tview.Table {
+	return av.table
+}
+
+// selectedID returns the ID of the selected alert
+func (av *AlertsView) selectedID() (int, bool) {
+	row, _ := av.table.GetSelection()
+	if row <= 0 || row >= av.table.GetRowCount() {
+		return 0, false
+	}
+	id, ok := av.table.GetCell(row, 0).GetReference().(int)
+	return id, ok
+}
+
+// Refresh redraws the alerts, newest first, keeping the selected alert
+func (av *AlertsView) Refresh() {
+	selectedID, hasSelection := av.selectedID()
+	row, _ := av.table.GetSelection()
+	offset, _ := av.table.GetOffset()
+
+	av.table.Clear()
+	for i, header := range alertsHeaders {
+		av.table.SetCell(0, i, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorWhite).
+			SetSelectable(false).
+			SetExpansion(1).
+			SetAttributes(tcell.AttrBold))
+	}
+
+	alerts := av.engine.Alerts()
+	for i, alert := range alerts {
+		cells := alertCells(alert)
+		cells[0].SetReference(alert.ID)
+		for col, cell := range cells {
+			av.table.SetCell(i+1, col, cell)
+		}
+		if hasSelection && alert.ID == selectedID {
+			row = i + 1
+		}
+	}
+
+	if row < 1 || row >= av.table.GetRowCount() {
+		row = 1
+	}
+	av.table.Select(row, 0)
+	av.table.SetOffset(offset, 0)
+
+	title := " Alerts "
+	if active, unacknowledged := av.engine.ActiveCounts(); active > 0 {
+		title += fmt.Sprintf("- [red]%d active, %d unacknowledged[-] ", active, unacknowledged)
+	} else {
+		title += fmt.Sprintf("- %d rules, none active ", len(av.engine.Rules()))
+	}
+	av.table.SetTitle(title)
+}
+
+// alertCells formats an alert as a table row, dimming resolved and acknowledged alerts
+func alertCells(alert Alert) []*tview.TableCell {
+	severityColor := tcell.ColorYellow
+	switch alert.Severity {
+	case AlertSeverityCritical:
+		severityColor = tcell.ColorRed
+	case AlertSeverityInfo:
+		severityColor = tcell.ColorSkyblue
+	}
+
+	resource := alert.ResourceName
+	if resource == "" {
+		resource = alert.ResourceType
+	}
+
+	state := "firing"
+	switch {
+	case alert.Resolved:
+		state = "resolved"
+	case alert.Acknowledged:
+		state = "acknowledged"
+	}
+	if !alert.SilencedUntil.IsZero() {
+		state += ", silenced until " + alert.SilencedUntil.Format("15:04")
+	}
+
+	cells := []*tview.TableCell{
+		tview.NewTableCell(alert.LastSeen.Format("2006-01-02 15:04:05")).SetTextColor(tcell.ColorWhite),
+		tview.NewTableCell(alert.Severity).SetTextColor(severityColor),
+		tview.NewTableCell(tview.Escape(alert.Rule)).SetTextColor(tcell.ColorYellow),
+		tview.NewTableCell(tview.Escape(resource)).SetTextColor(tcell.ColorAqua),
+		tview.NewTableCell(tview.Escape(alert.Message)).SetTextColor(tcell.ColorWhite),
+		tview.NewTableCell(strconv.Itoa(alert.Count)).SetTextColor(tcell.ColorWhite).SetAlign(tview.AlignRight),
+		tview.NewTableCell(state).SetTextColor(severityColor),
+	}
+	if alert.Resolved || alert.Acknowledged {
+		for _, cell := range cells {
+			cell.SetTextColor(tcell.ColorGray).SetAttributes(tcell.AttrDim)
+		}
+	}
+	return cells
+}
@@ -0,0 +1,435 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// APIPrefix is the path prefix of the JSON API
+	APIPrefix = "/api/v1/"
+
+	// APIUITimeout limits how long a request waits for the UI to run it
+	APIUITimeout = 5 * time.Second
+
+	// unixAddrPrefix marks API addresses that are Unix socket paths
+	unixAddrPrefix = "unix:"
+)
+
+// errUIUnavailable is returned when the UI doesn't run a request in time
+var errUIUnavailable = errors.New("dashboard UI is not responding")
+
+// APIPod is a pod with the node it runs on
+type APIPod struct {
+	Node string
+	PodInfo
+}
+
+// APISearch is the search filter of the dashboard
+type APISearch struct {
+	Query  string `json:"query"`
+	Active bool   `json:"active"`
+}
+
+// APINavigation selects a node, or the pods of one of its namespaces
+type APINavigation struct {
+	Node      string `json:"node"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// APIView is the current view of the dashboard
+type APIView struct {
+	View         string `json:"view"`                   // main, details, pods, logs, edit or changes
+	SelectedNode string `json:"selectedNode,omitempty"` // Node of the selected main table row
+}
+
+// APIServer exposes the state of a running dashboard as JSON and lets other
+// tools refresh it, set the search filter and navigate. Requests touching the
+// UI run on the UI goroutine.
+type APIServer struct {
+	app *App
+}
+
+// NewAPIServer creates the API of a dashboard
+func NewAPIServer(app *App) *APIServer {
+	return &APIServer{app: app}
+}
+
+// Handler returns the handler serving the API below APIPrefix
+func (s *APIServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	s.register(mux)
+	return s.checkHost(mux)
+}
+
+// register adds the API endpoints to mux
+func (s *APIServer) register(mux *http.ServeMux) {
+	mux.HandleFunc(APIPrefix+"nodes", s.methods(map[string]http.HandlerFunc{http.MethodGet: s.getNodes}))
+	mux.HandleFunc(APIPrefix+"nodes/", s.methods(map[string]http.HandlerFunc{http.MethodGet: s.getNode}))
+	mux.HandleFunc(APIPrefix+"pods", s.methods(map[string]http.HandlerFunc{http.MethodGet: s.getPods}))
+	mux.HandleFunc(APIPrefix+"filtered", s.methods(map[string]http.HandlerFunc{http.MethodGet: s.getFiltered}))
+	mux.HandleFunc(APIPrefix+"changes", s.methods(map[string]http.HandlerFunc{http.MethodGet: s.getChanges}))
+	mux.HandleFunc(APIPrefix+"search", s.methods(map[string]http.HandlerFunc{
+		http.MethodGet:    s.getSearch,
+		http.MethodPut:    s.changesState(s.setSearch),
+		http.MethodPost:   s.changesState(s.setSearch),
+		http.MethodDelete: s.changesState(s.clearSearch),
+	}))
+	mux.HandleFunc(APIPrefix+"refresh", s.methods(map[string]http.HandlerFunc{http.MethodPost: s.changesState(s.refresh)}))
+	mux.HandleFunc(APIPrefix+"view", s.methods(map[string]http.HandlerFunc{http.MethodGet: s.getView}))
+	mux.HandleFunc(APIPrefix+"navigate", s.methods(map[string]http.HandlerFunc{http.MethodPost: s.changesState(s.navigate)}))
+}
+
+// checkHost rejects requests on a TCP address whose Host header names another
+// host than the one listened on, so that a web page whose domain was rebound
+// to a local address can't read the API as same-origin. IP addresses and
+// localhost are accepted, a browser can only send those when they are the
+// page's own origin.
+func (s *APIServer) checkHost(handler http.Handler) http.Handler {
+	listenHost, _, _ := net.SplitHostPort(s.app.config.APIAddr)
+	unixSocket := strings.HasPrefix(s.app.config.APIAddr, unixAddrPrefix)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host // No port
+		}
+		host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+		allowed := unixSocket || net.ParseIP(host) != nil || strings.EqualFold(host, "localhost") ||
+			(listenHost != "" && strings.EqualFold(host, listenHost))
+		if !allowed {
+			writeAPIError(w, http.StatusForbidden, fmt.Errorf("host %q is not allowed, use localhost or an IP address", r.Host))
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// methods dispatches a request by method, rejecting the others
+func (s *APIServer) methods(handlers map[string]http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := handlers[r.Method]
+		if !ok {
+			var allowed []string
+			for method := range handlers {
+				allowed = append(allowed, method)
+			}
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// changesState guards the handler of a request changing the dashboard. Cross-origin
+// requests are rejected so that web pages can't drive a dashboard listening on
+// localhost, and a body must be JSON, which browsers can't send to other origins
+// without permission.
+func (s *APIServer) changesState(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				writeAPIError(w, http.StatusForbidden, fmt.Errorf("cross-origin requests from %q are not allowed", origin))
+				return
+			}
+		}
+		if r.ContentLength != 0 || r.Header.Get("Content-Type") != "" {
+			if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+				writeAPIError(w, http.StatusUnsupportedMediaType, errors.New("request body must be application/json"))
+				return
+			}
+		}
+		handler(w, r)
+	}
+}
+
+// onUI runs fn on the UI goroutine and waits for it. If the UI doesn't pick
+// fn up within APIUITimeout, fn is cancelled and never runs, so a request
+// answered with an error doesn't change the dashboard later.
+func (s *APIServer) onUI(fn func()) error {
+	const (
+		queued = iota
+		started
+		cancelled
+	)
+	var state atomic.Int32
+	done := make(chan struct{})
+	go s.app.ui.app.QueueUpdateDraw(func() {
+		if !state.CompareAndSwap(queued, started) {
+			return
+		}
+		fn()
+		close(done)
+	})
+	select {
+	case <-done:
+		return nil
+	case <-time.After(APIUITimeout):
+		if state.CompareAndSwap(queued, cancelled) {
+			return errUIUnavailable
+		}
+		<-done // Already running, wait for it to finish
+		return nil
+	}
+}
+
+// getNodes returns the nodes and pods of the namespaces shown, without the search filter
+func (s *APIServer) getNodes(w http.ResponseWriter, r *http.Request) {
+	var snapshot *Snapshot
+	if err := s.onUI(func() {
+		snapshot = NewSnapshot(s.app.provider.GetClusterName(),
+			s.app.ui.nodeView.GetLastNodeData(), s.app.ui.nodeView.GetLastPodData())
+	}); err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, snapshot)
+}
+
+// getNode returns one node with its pods, with glyphs as PodIndicators like in snapshots
+func (s *APIServer) getNode(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, APIPrefix+"nodes/")
+	var node NodeData
+	var found bool
+	if err := s.onUI(func() {
+		node, found = s.app.ui.nodeView.GetLastNodeData()[name]
+		podData := s.app.ui.nodeView.GetLastPodData()[name]
+		node.PodIndicators = podIndicatorGlyphs(podData, sortedKeys(podData, nil))
+	}); err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if !found {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("node %q not found", name))
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, node)
+}
+
+// getPods returns the pods, optionally of one node and namespace, sorted by node and name
+func (s *APIServer) getPods(w http.ResponseWriter, r *http.Request) {
+	nodeName, namespace := r.URL.Query().Get("node"), r.URL.Query().Get("namespace")
+	pods := []APIPod{}
+	if err := s.onUI(func() {
+		nodeData := s.app.ui.nodeView.GetLastNodeData()
+		for _, name := range sortedKeys(nodeData, nil) {
+			if nodeName != "" && name != nodeName {
+				continue
+			}
+			node := nodeData[name]
+			for _, podName := range sortedKeys(node.Pods, nil) {
+				if pod := node.Pods[podName]; namespace == "" || pod.Namespace == namespace {
+					pods = append(pods, APIPod{Node: name, PodInfo: pod})
+				}
+			}
+		}
+	}); err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, pods)
+}
+
+// getFiltered returns the data shown in the main table, with the search filter applied
+func (s *APIServer) getFiltered(w http.ResponseWriter, r *http.Request) {
+	var snapshot *Snapshot
+	if err := s.onUI(func() {
+		nodeData, podsByNode := s.app.ui.nodeView.GetLastNodeData(), s.app.ui.nodeView.GetLastPodData()
+		if search := s.app.GetSearchState(); search.Active {
+			nodeData, podsByNode = s.app.ui.nodeView.GetFilteredData(search.Query)
+		}
+		snapshot = NewSnapshot(s.app.provider.GetClusterName(), nodeData, podsByNode)
+	}); err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, snapshot)
+}
+
+// getChanges returns the change log, newest first, optionally filtered by a
+// change log filter query and limited in number
+func (s *APIServer) getChanges(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseChangeLogFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid filter: %v", err))
+		return
+	}
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", value))
+			return
+		}
+	}
+
+	var changes []ChangeEvent
+	if err := s.onUI(func() {
+		changes = s.app.ui.changeLogView.Changes()
+	}); err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	cluster := s.app.provider.GetClusterName()
+	records := []ChangeRecord{}
+	for i := len(changes) - 1; i >= 0 && (limit == 0 || len(records) < limit); i-- {
+		if filter.Matches(changes[i]) {
+			records = append(records, NewChangeRecord(cluster, changes[i]))
+		}
+	}
+	writeAPIJSON(w, http.StatusOK, records)
+}
+
+// getSearch returns the search filter
+func (s *APIServer) getSearch(w http.ResponseWriter, r *http.Request) {
+	var search APISearch
+	if err := s.onUI(func() {
+		state := s.app.GetSearchState()
+		search = APISearch{Query: state.Query, Active: state.Active}
+	}); err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, search)
+}
+
+// setSearch applies the search filter of an APISearch body, an empty query clears it
+func (s *APIServer) setSearch(w http.ResponseWriter, r *http.Request) {
+	var search APISearch
+	if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %v", err))
+		return
+	}
+	s.applySearch(w, search.Query)
+}
+
+// clearSearch clears the search filter
+func (s *APIServer) clearSearch(w http.ResponseWriter, r *http.Request) {
+	s.applySearch(w, "")
+}
+
+// applySearch sets the search filter and returns it
+func (s *APIServer) applySearch(w http.ResponseWriter, query string) {
+	if err := s.onUI(func() {
+		s.app.ui.SetSearch(query)
+	}); err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, APISearch{Query: query, Active: query != ""})
+}
+
+// refresh triggers a refresh, which runs in the background
+func (s *APIServer) refresh(w http.ResponseWriter, r *http.Request) {
+	s.app.TriggerRefresh()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// getView returns the current view
+func (s *APIServer) getView(w http.ResponseWriter, r *http.Request) {
+	var view APIView
+	if err := s.onUI(func() {
+		view.View, view.SelectedNode = s.app.ui.CurrentView()
+	}); err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, view)
+}
+
+// navigate selects the node of an APINavigation body, or shows its namespace pods
+func (s *APIServer) navigate(w http.ResponseWriter, r *http.Request) {
+	var navigation APINavigation
+	if err := json.NewDecoder(r.Body).Decode(&navigation); err != nil || navigation.Node == "" {
+		writeAPIError(w, http.StatusBadRequest, errors.New("invalid body: expected {\"node\": ..., \"namespace\": ...}"))
+		return
+	}
+	var navErr error
+	var view APIView
+	if err := s.onUI(func() {
+		navErr = s.app.ui.NavigateTo(navigation.Node, navigation.Namespace)
+		view.View, view.SelectedNode = s.app.ui.CurrentView()
+	}); err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if navErr != nil {
+		writeAPIError(w, http.StatusNotFound, navErr)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, view)
+}
+
+// writeAPIJSON writes a JSON response
+func writeAPIJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError writes an error as {"error": message}
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeAPIJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// unixSocketListener removes its socket file when closed
+type unixSocketListener struct {
+	*net.UnixListener
+	path string
+}
+
+// Close implements net.Listener
+func (l *unixSocketListener) Close() error {
+	err := l.UnixListener.Close()
+	os.Remove(l.path)
+	return err
+}
+
+// listen listens on a TCP address, or on a Unix socket for addresses
+// prefixed with unix:. A stale socket file left by a previous session is
+// replaced, and the socket is only accessible to the current user: it's
+// created in a private directory and moved into place once restricted.
+func listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, unixAddrPrefix)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s is in use by another session", path)
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".kubism-api-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmpPath := filepath.Join(dir, "api.sock")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmpPath, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	listener.SetUnlinkOnClose(false) // The socket is moved, the wrapper removes it
+	if err := os.Chmod(tmpPath, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &unixSocketListener{UnixListener: listener, path: path}, nil
+}
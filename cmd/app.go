@@ -2,8 +2,13 @@ package cmd
 
 import (
 	"fmt"
+	"net/http"
 	"sync/atomic"
+	"text/template"
 	"time"
+
+	"github.com/rivo/tview"
+	corev1 "k8s.io/api/core/v1"
 )
 
 // Config holds the application configuration
@@ -12,6 +17,66 @@ type Config struct {
 	ExcludeNamespaces map[string]bool
 	UseMockData       bool
 	LogFilePath       string
+	LogFileFormat     string        // text, jsonl or csv, derived from the file extension if empty
+	LogFileMaxSize    int64         // Rotate the log file at this size in bytes, zero to disable
+	LogFileRotate     time.Duration // Rotate the log file at this interval, zero to disable
+	LogFileMaxBackups int           // Rotated log files to keep, zero to keep all
+	LogFileMaxAge     time.Duration // Delete rotated log files older than this, zero to keep all
+	LogFileHistory    int           // Entries reloaded from a structured log file at startup
+	ChangeLogSize     int           // Change events kept in memory
+	LogMaxLines       int
+	LogExportDir      string
+	AlertRules        []AlertRule // Rules loaded from the rules file, no alerts pane if empty
+	Webhooks          []string    // URLs change events and alerts are posted to
+	WebhookTemplate   *template.Template
+	ExecHooks         [][]string // Programs and arguments run with the event JSON on stdin, see SplitCommand
+	SinkFile          string     // File event JSON lines are appended to
+	SinkDeliver       string     // What sinks receive: all, changes or alerts
+	MetricsAddr       string     // Address the Prometheus metrics endpoint listens on, empty to disable
+	HTTPAddr          string     // Address the web dashboard listens on, empty to disable
+	APIAddr           string     // Address of the JSON API, host:port or unix:path, empty to disable
+	SearchQuery       string     // Initial search filter on pod names
+	ChangeFilter      string     // Initial change log filter, see parseChangeLogFilter
+	OutputFormat      string     // Output format of the watch and snapshot commands
+}
+
+// Sinks returns the configured outbound sinks
+func (c *Config) Sinks() []Sink {
+	var sinks []Sink
+	for _, url := range c.Webhooks {
+		sinks = append(sinks, &WebhookSink{URL: url, Template: c.WebhookTemplate, Client: &http.Client{Timeout: SinkTimeout}})
+	}
+	for _, args := range c.ExecHooks {
+		sinks = append(sinks, &ExecSink{Command: args})
+	}
+	if c.SinkFile != "" {
+		sinks = append(sinks, &FileSink{Path: c.SinkFile})
+	}
+	return sinks
+}
+
+// deliversToSinks reports whether sinks receive payloads of the given kind
+func (c *Config) deliversToSinks(kind string) bool {
+	switch c.SinkDeliver {
+	case SinkDeliverChanges:
+		return kind == SinkPayloadChange
+	case SinkDeliverAlerts:
+		return kind == SinkPayloadAlert
+	}
+	return true
+}
+
+// ChangeLogFileOptions returns the change log file settings
+func (c *Config) ChangeLogFileOptions() ChangeLogFileOptions {
+	return ChangeLogFileOptions{
+		Path:           c.LogFilePath,
+		Format:         c.LogFileFormat,
+		MaxSize:        c.LogFileMaxSize,
+		RotateInterval: c.LogFileRotate,
+		MaxBackups:     c.LogFileMaxBackups,
+		MaxAge:         c.LogFileMaxAge,
+		History:        c.LogFileHistory,
+	}
 }
 
 // SearchState holds the current search/filter state
@@ -28,27 +93,27 @@ type App struct {
 	provider       K8sProvider
 	ui             *UI
 	stateCache     *StateCache
+	alertEngine    *AlertEngine    // Nil without alert rules
+	sinks          *SinkDispatcher // Nil without sinks
+	metrics        *Metrics        // Nil without a metrics address
+	web            *WebDashboard   // Nil without an HTTP address
+	api            *APIServer      // Nil without an API address
 	isRefreshing   atomic.Bool
 	spinnerIndex   atomic.Int32
 	showingDetails bool
 	showingPods    bool
 	hasError       atomic.Bool
-	refreshChan    chan struct{} // Channel for triggering refreshes
-	searchState    SearchState   // Track search/filter state
+	refreshChan    chan struct{}    // Channel for triggering refreshes
+	searchState    SearchState      // Track search/filter state
+	refreshedNodes map[string]bool  // Nodes of the last refresh, only used by refreshData
+	resources      *resourceTracker // Workloads and events, only used by refreshData
 }
 
 // NewApp creates a new application instance
 func NewApp(config *Config) (*App, error) {
-	var provider K8sProvider
-	var err error
-
-	if config.UseMockData {
-		provider = NewMockK8sDataProvider()
-	} else {
-		provider, err = NewRealK8sDataProvider()
-		if err != nil {
-			return nil, fmt.Errorf("failed to create K8s provider: %v", err)
-		}
+	provider, err := newProvider(config)
+	if err != nil {
+		return nil, err
 	}
 
 	app := &App{
@@ -56,7 +121,40 @@ func NewApp(config *Config) (*App, error) {
 		provider:    provider,
 		stateCache:  NewStateCache(),
 		refreshChan: make(chan struct{}, 1), // Buffered channel to prevent blocking
-		searchState: SearchState{},          // Initialize search state
+		searchState: SearchState{ // Start with the search filter from the command line
+			Active: config.SearchQuery != "",
+			Query:  config.SearchQuery,
+		},
+	}
+	if len(config.AlertRules) > 0 {
+		app.alertEngine = NewAlertEngine(config.AlertRules)
+	}
+
+	if config.MetricsAddr != "" {
+		app.metrics = NewMetrics(provider.GetClusterName())
+	}
+	if config.HTTPAddr != "" {
+		app.web = NewWebDashboard(config.ChangeLogSize)
+	}
+	if config.APIAddr != "" {
+		app.api = NewAPIServer(app)
+	}
+
+	// Deliver change events and alerts to the configured sinks
+	if sinks := config.Sinks(); len(sinks) > 0 {
+		app.sinks = NewSinkDispatcher(sinks, DefaultSinkRetry)
+		app.sinks.SetErrorFunc(func(sink Sink, err error) {
+			// Don't block the caller, which may be the refresh goroutine or a stopped UI
+			go app.ui.app.QueueUpdateDraw(func() {
+				app.ui.changeLogView.SetStatus(fmt.Sprintf("[red]%s: %s[-]", tview.Escape(sink.Name()), tview.Escape(err.Error())))
+			})
+		})
+		if app.alertEngine != nil && config.deliversToSinks(SinkPayloadAlert) {
+			cluster := provider.GetClusterName()
+			app.alertEngine.SetAlertFunc(func(alert Alert) {
+				app.sinks.Deliver(NewAlertPayload(alert, cluster))
+			})
+		}
 	}
 
 	// Create UI components
@@ -68,6 +166,18 @@ func NewApp(config *Config) (*App, error) {
 	return app, nil
 }
 
+// newProvider creates the mock or real data provider
+func newProvider(config *Config) (K8sProvider, error) {
+	if config.UseMockData {
+		return NewMockK8sDataProvider(), nil
+	}
+	provider, err := NewRealK8sDataProvider()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create K8s provider: %v", err)
+	}
+	return provider, nil
+}
+
 // GetSearchState returns the current search state
 func (a *App) GetSearchState() *SearchState {
 	return &a.searchState
@@ -76,6 +186,7 @@ func (a *App) GetSearchState() *SearchState {
 // Run starts the application
 func (a *App) Run() error {
 	// Initial data load without changelog updates
+	start := time.Now()
 	nodeData, podsByNode, err := a.provider.UpdateNodeData(
 		a.config.IncludeNamespaces,
 		a.config.ExcludeNamespaces,
@@ -84,10 +195,24 @@ func (a *App) Run() error {
 		return fmt.Errorf("failed to load initial data: %v", err)
 	}
 
-	// Update nodeView's map with the provider's map
-	for k, v := range a.provider.GetNodeMap() {
-		a.ui.nodeView.GetNodeMap()[k] = v
+	if a.metrics != nil {
+		a.metrics.ObserveRefresh(time.Since(start), nil)
+		a.metrics.SetState(nodeData)
+	}
+	if a.web != nil {
+		a.web.Update(NewSnapshot(a.provider.GetClusterName(), nodeData, podsByNode))
+	}
+
+	// Serve metrics, the web dashboard and the API, failing early if an address is unusable
+	closeServers, err := a.startServers()
+	if err != nil {
+		return err
 	}
+	defer closeServers()
+
+	// Update nodeView's map with the provider's map
+	a.ui.nodeView.SetNodeMap(copyNodeMap(a.provider.GetNodeMap()))
+	a.refreshedNodes = nodeNames(nodeData)
 
 	// Update UI with initial data
 	a.ui.UpdateTable(nodeData, podsByNode)
@@ -99,6 +224,16 @@ func (a *App) Run() error {
 			Timestamp: time.Now(),
 		})
 	}
+	a.resources = &resourceTracker{provider: a.provider, stateCache: a.stateCache}
+	if _, err := a.resources.changes(a.config.IncludeNamespaces, a.config.ExcludeNamespaces); err != nil && a.metrics != nil {
+		a.metrics.CountAPIError()
+	}
+
+	// Start timing state alert rules
+	if a.alertEngine != nil {
+		a.alertEngine.EvaluateState(nodeData, time.Now())
+		a.ui.alertsView.Refresh()
+	}
 
 	// Set up refresh handler
 	go func() {
@@ -141,6 +276,10 @@ func (a *App) Run() error {
 	defer spinnerTicker.Stop()
 
 	// Run the application
+	defer a.ui.changeLogView.Close()
+	if a.sinks != nil {
+		defer a.sinks.Close(SinkCloseTimeout)
+	}
 	if err := a.ui.app.Run(); err != nil {
 		return fmt.Errorf("application error: %v", err)
 	}
@@ -148,6 +287,46 @@ func (a *App) Run() error {
 	return nil
 }
 
+// startServers starts the HTTP servers of the metrics endpoint, the web
+// dashboard and the API, sharing one if they use the same address, and
+// returns a function closing them
+func (a *App) startServers() (func(), error) {
+	muxes := make(map[string]*http.ServeMux)
+	mux := func(addr string) *http.ServeMux {
+		if muxes[addr] == nil {
+			muxes[addr] = http.NewServeMux()
+		}
+		return muxes[addr]
+	}
+	if a.metrics != nil {
+		mux(a.config.MetricsAddr).Handle("/metrics", a.metrics)
+	}
+	if a.web != nil {
+		mux(a.config.HTTPAddr).Handle("/", a.web.Handler())
+	}
+	if a.api != nil {
+		mux(a.config.APIAddr).Handle(APIPrefix, a.api.Handler())
+	}
+
+	var servers []*http.Server
+	closeServers := func() {
+		for _, server := range servers {
+			server.Close()
+		}
+	}
+	for addr, handler := range muxes {
+		listener, err := listen(addr)
+		if err != nil {
+			closeServers()
+			return nil, fmt.Errorf("failed to listen on %s: %v", addr, err)
+		}
+		server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+		go server.Serve(listener)
+		servers = append(servers, server)
+	}
+	return closeServers, nil
+}
+
 // TriggerRefresh sends a signal to refresh the data
 func (a *App) TriggerRefresh() {
 	select {
@@ -175,16 +354,14 @@ func (a *App) retryInBackground() {
 			a.config.IncludeNamespaces,
 			a.config.ExcludeNamespaces,
 		)
+		if err != nil && a.metrics != nil {
+			a.metrics.CountAPIError()
+		}
 		if err == nil && len(nodeData) > 0 {
 			// Success with valid data - update UI and dismiss error
+			nodeMap := copyNodeMap(a.provider.GetNodeMap())
 			a.ui.app.QueueUpdateDraw(func() {
-				// Update nodeView's map
-				for k := range a.ui.nodeView.GetNodeMap() {
-					delete(a.ui.nodeView.GetNodeMap(), k)
-				}
-				for k, v := range a.provider.GetNodeMap() {
-					a.ui.nodeView.GetNodeMap()[k] = v
-				}
+				a.ui.nodeView.SetNodeMap(nodeMap)
 
 				// Update UI and dismiss error
 				a.ui.UpdateTable(nodeData, podsByNode)
@@ -213,60 +390,139 @@ func (a *App) refreshData() error {
 		})
 	}()
 
-	// Don't refresh if showing details or pods
-	if a.showingDetails || a.showingPods {
-		return nil
-	}
-
 	// Get fresh data
+	start := time.Now()
 	nodeData, podsByNode, err := a.provider.UpdateNodeData(
 		a.config.IncludeNamespaces,
 		a.config.ExcludeNamespaces,
 	)
+	if a.metrics != nil {
+		a.metrics.ObserveRefresh(time.Since(start), err)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to refresh data: %v", err)
 	}
 
-	// Check for changes and update changelog
+	// Check for changes
+	var changes []ChangeEvent
 	for nodeName, newData := range nodeData {
-		changes := a.stateCache.Compare(nodeName, ResourceState{
+		changes = append(changes, a.stateCache.Compare(nodeName, ResourceState{
 			Data:      newData,
 			Timestamp: time.Now(),
-		})
-		for _, change := range changes {
-			a.ui.changeLogView.AddChange(change)
-		}
+		})...)
 	}
 
 	// Check for removed nodes
-	for nodeName := range a.ui.nodeView.GetNodeMap() {
+	for nodeName := range a.refreshedNodes {
 		if _, exists := nodeData[nodeName]; !exists {
-			changes := a.stateCache.Compare(nodeName, ResourceState{
+			changes = append(changes, a.stateCache.Compare(nodeName, ResourceState{
 				Data:      nil,
 				Timestamp: time.Now(),
-			})
-			for _, change := range changes {
-				a.ui.changeLogView.AddChange(change)
-			}
+			})...)
 		}
 	}
 
-	// Update nodeView's map
-	for k := range a.ui.nodeView.GetNodeMap() {
-		delete(a.ui.nodeView.GetNodeMap(), k)
+	// Check workloads and events, whose errors don't fail the refresh of nodes
+	resourceChanges, err := a.resources.changes(a.config.IncludeNamespaces, a.config.ExcludeNamespaces)
+	if err != nil && a.metrics != nil {
+		a.metrics.CountAPIError()
+	}
+	changes = append(changes, resourceChanges...)
+
+	// Export the new state and changes as metrics
+	if a.metrics != nil {
+		a.metrics.SetState(nodeData)
+		a.metrics.CountChanges(changes)
 	}
-	for k, v := range a.provider.GetNodeMap() {
-		a.ui.nodeView.GetNodeMap()[k] = v
+
+	// Push the new state and changes to the web dashboard
+	if a.web != nil {
+		cluster := a.provider.GetClusterName()
+		records := make([]ChangeRecord, len(changes))
+		for i, change := range changes {
+			records[i] = NewChangeRecord(cluster, change)
+		}
+		a.web.AddChanges(records)
+		a.web.Update(NewSnapshot(cluster, nodeData, podsByNode))
+	}
+
+	// Deliver changes to the sinks
+	if a.sinks != nil && a.config.deliversToSinks(SinkPayloadChange) {
+		cluster := a.provider.GetClusterName()
+		for _, change := range changes {
+			a.sinks.Deliver(NewChangePayload(change, cluster))
+		}
 	}
 
-	// Update the UI
+	// Raise alerts for matching changes and states
+	if a.alertEngine != nil {
+		for _, change := range changes {
+			a.alertEngine.ProcessChange(change)
+		}
+		a.alertEngine.EvaluateState(nodeData, time.Now())
+	}
+
+	// Update the changelog on the UI goroutine, it keeps the history and table in sync
+	if len(changes) > 0 {
+		a.ui.app.QueueUpdateDraw(func() {
+			for _, change := range changes {
+				a.ui.changeLogView.AddChange(change)
+			}
+		})
+	}
+
+	a.refreshedNodes = nodeNames(nodeData)
+
+	// Swap nodeView's map on the UI goroutine, which reads it, along with the table
+	nodeMap := copyNodeMap(a.provider.GetNodeMap())
 	a.ui.app.QueueUpdateDraw(func() {
+		a.ui.nodeView.SetNodeMap(nodeMap)
 		a.ui.UpdateTable(nodeData, podsByNode)
+		a.ui.refreshDetailViews()
+		if a.ui.alertsView != nil {
+			a.ui.alertsView.Refresh()
+		}
 	})
 
 	return nil
 }
 
+// copyNodeMap copies a provider's node map, which changes on every update
+func copyNodeMap(nodeMap map[string]*corev1.Node) map[string]*corev1.Node {
+	nodes := make(map[string]*corev1.Node, len(nodeMap))
+	for name, node := range nodeMap {
+		nodes[name] = node
+	}
+	return nodes
+}
+
+// nodeNames returns the set of node names of node data
+func nodeNames(nodeData map[string]NodeData) map[string]bool {
+	names := make(map[string]bool, len(nodeData))
+	for name := range nodeData {
+		names[name] = true
+	}
+	return names
+}
+
+// GetAlertEngine returns the alert engine, nil without alert rules
+func (a *App) GetAlertEngine() *AlertEngine {
+	return a.alertEngine
+}
+
+// GetKubeClient returns the Kubernetes client when connected to a real cluster
+func (a *App) GetKubeClient() (*KubeClientWrapper, bool) {
+	if provider, ok := a.provider.(*RealK8sDataProvider); ok {
+		return provider.client, true
+	}
+	return nil, false
+}
+
+// GetStateCache returns the cache of the last seen resource states
+func (a *App) GetStateCache() *StateCache {
+	return a.stateCache
+}
+
 // GetProvider returns the K8s provider
 func (a *App) GetProvider() K8sProvider {
 	return a.provider
@@ -1,7 +1,8 @@
 package cmd
 
 import (
-	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -18,6 +19,7 @@ type ResourceState struct {
 type ChangeEvent struct {
 	ResourceType string // "Node", "Pod", etc
 	ResourceName string // Name of the resource that changed
+	Namespace    string // Namespace of namespaced resources, empty for nodes
 	ChangeType   string // "Added", "Removed", "Modified"
 	Field        string // Specific field that changed
 	OldValue     interface{}
@@ -25,16 +27,25 @@ type ChangeEvent struct {
 	Timestamp    time.Time
 }
 
+// MaxRemovedStates is the number of removed resources whose last state is kept
+const MaxRemovedStates = 1000
+
 // StateCache provides thread-safe caching and comparison of resource states
 type StateCache struct {
-	mu    sync.RWMutex
-	cache map[string]ResourceState
+	mu      sync.RWMutex
+	cache   map[string]ResourceState
+	removed map[string]ResourceState // Last state of removed nodes and pods, Timestamp is the removal time
+
+	comparators *ComparatorRegistry
 }
 
 // NewStateCache creates a new StateCache instance
 func NewStateCache() *StateCache {
 	return &StateCache{
-		cache: make(map[string]ResourceState),
+		cache:   make(map[string]ResourceState),
+		removed: make(map[string]ResourceState),
+
+		comparators: NewComparatorRegistry(),
 	}
 }
 
@@ -53,202 +64,141 @@ func (sc *StateCache) Get(key string) (ResourceState, bool) {
 	return state, exists
 }
 
-// Compare compares a new state with the cached state and returns changes
-func (sc *StateCache) Compare(key string, newState ResourceState) []ChangeEvent {
-	sc.mu.Lock()
-	defer sc.mu.Unlock()
-
-	oldState, exists := sc.cache[key]
-	var changes []ChangeEvent
+// GetLastKnown returns the state of a node ("node") or pod ("node/pod"), the
+// key format used in ChangeEvent.ResourceName. For removed resources the
+// state at removal is returned and removed is true.
+func (sc *StateCache) GetLastKnown(key string) (state ResourceState, removed bool, found bool) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
 
-	if !exists {
-		// New resource added
-		changes = append(changes, ChangeEvent{
-			ResourceType: "Node",
-			ResourceName: key,
-			ChangeType:   "Added",
-			NewValue:     newState.Data,
-			Timestamp:    time.Now(),
-		})
-	} else if newState.Data == nil {
-		// Resource removed
-		changes = append(changes, ChangeEvent{
-			ResourceType: "Node",
-			ResourceName: key,
-			ChangeType:   "Removed",
-			OldValue:     oldState.Data,
-			Timestamp:    time.Now(),
-		})
-	} else {
-		// Compare specific fields we care about
-		oldData, ok := oldState.Data.(NodeData)
-		if !ok {
-			return changes
+	nodeName, podName, isPod := strings.Cut(key, "/")
+	if current, ok := sc.cache[nodeName]; ok {
+		if !isPod {
+			return current, false, true
 		}
-
-		newData, ok := newState.Data.(NodeData)
-		if !ok {
-			return changes
+		if data, ok := current.Data.(NodeData); ok {
+			if pod, ok := data.Pods[podName]; ok {
+				return ResourceState{Data: pod, Timestamp: current.Timestamp}, false, true
+			}
 		}
+	}
+	if last, ok := sc.removed[key]; ok {
+		return last, true, true
+	}
 
-		// Check node status
-		if oldData.Status != newData.Status {
-			changes = append(changes, ChangeEvent{
-				ResourceType: "Node",
-				ResourceName: key,
-				ChangeType:   "Modified",
-				Field:        "Status",
-				OldValue:     oldData.Status,
-				NewValue:     newData.Status,
-				Timestamp:    time.Now(),
-			})
+	// Pods of a removed node are kept with the node
+	if last, ok := sc.removed[nodeName]; ok && isPod {
+		if data, ok := last.Data.(NodeData); ok {
+			if pod, ok := data.Pods[podName]; ok {
+				return ResourceState{Data: pod, Timestamp: last.Timestamp}, true, true
+			}
 		}
+	}
+	return ResourceState{}, false, false
+}
 
-		// Check node version
-		if oldData.Version != newData.Version {
-			changes = append(changes, ChangeEvent{
-				ResourceType: "Node",
-				ResourceName: key,
-				ChangeType:   "Modified",
-				Field:        "Version",
-				OldValue:     oldData.Version,
-				NewValue:     newData.Version,
-				Timestamp:    time.Now(),
-			})
+// remember keeps the last state of a removed resource, forgetting the oldest
+// removals beyond MaxRemovedStates. Must be called with the lock held.
+func (sc *StateCache) remember(key string, data interface{}, removedAt time.Time) {
+	sc.removed[key] = ResourceState{Data: data, Timestamp: removedAt}
+	for len(sc.removed) > MaxRemovedStates {
+		oldestKey := ""
+		var oldest time.Time
+		for k, state := range sc.removed {
+			if oldestKey == "" || state.Timestamp.Before(oldest) {
+				oldestKey, oldest = k, state.Timestamp
+			}
 		}
+		delete(sc.removed, oldestKey)
+	}
+}
 
-		// Check pod count
-		if oldData.PodCount != newData.PodCount {
-			changes = append(changes, ChangeEvent{
-				ResourceType: "Node",
-				ResourceName: key,
-				ChangeType:   "Modified",
-				Field:        "PodCount",
-				OldValue:     oldData.PodCount,
-				NewValue:     newData.PodCount,
-				Timestamp:    time.Now(),
-			})
-		}
+// Compare compares the new state of a node with the cached state and returns
+// the changes of the node, its pods and their containers
+func (sc *StateCache) Compare(key string, newState ResourceState) []ChangeEvent {
+	return sc.CompareResource("Node", key, newState)
+}
 
-		// Compare pod states
-		for podName, newPod := range newData.Pods {
-			oldPod, exists := oldData.Pods[podName]
-			if !exists {
-				// New pod added
-				changes = append(changes, ChangeEvent{
-					ResourceType: "Pod",
-					ResourceName: fmt.Sprintf("%s/%s", key, podName),
-					ChangeType:   "Added",
-					Field:        "Status",
-					NewValue:     newPod.Status,
-					Timestamp:    time.Now(),
-				})
-			} else {
-				// Check pod status changes
-				if oldPod.Status != newPod.Status {
-					changes = append(changes, ChangeEvent{
-						ResourceType: "Pod",
-						ResourceName: fmt.Sprintf("%s/%s", key, podName),
-						ChangeType:   "Modified",
-						Field:        "Status",
-						OldValue:     oldPod.Status,
-						NewValue:     newPod.Status,
-						Timestamp:    time.Now(),
-					})
-				}
-
-				// Check pod restart count changes
-				if oldPod.RestartCount != newPod.RestartCount {
-					changes = append(changes, ChangeEvent{
-						ResourceType: "Pod",
-						ResourceName: fmt.Sprintf("%s/%s", key, podName),
-						ChangeType:   "Modified",
-						Field:        "RestartCount",
-						OldValue:     oldPod.RestartCount,
-						NewValue:     newPod.RestartCount,
-						Timestamp:    time.Now(),
-					})
-				}
-
-				// Check container changes
-				for containerName, newContainer := range newPod.ContainerInfo {
-					oldContainer, exists := oldPod.ContainerInfo[containerName]
-					if !exists {
-						// New container added
-						changes = append(changes, ChangeEvent{
-							ResourceType: "Container",
-							ResourceName: fmt.Sprintf("%s/%s/%s", key, podName, containerName),
-							ChangeType:   "Added",
-							Field:        "Status",
-							NewValue:     newContainer.Status,
-							Timestamp:    time.Now(),
-						})
-					} else {
-						// Check container status changes
-						if oldContainer.Status != newContainer.Status {
-							changes = append(changes, ChangeEvent{
-								ResourceType: "Container",
-								ResourceName: fmt.Sprintf("%s/%s/%s", key, podName, containerName),
-								ChangeType:   "Modified",
-								Field:        "Status",
-								OldValue:     oldContainer.Status,
-								NewValue:     newContainer.Status,
-								Timestamp:    time.Now(),
-							})
-						}
-
-						// Check container restart count changes
-						if oldContainer.RestartCount != newContainer.RestartCount {
-							changes = append(changes, ChangeEvent{
-								ResourceType: "Container",
-								ResourceName: fmt.Sprintf("%s/%s/%s", key, podName, containerName),
-								ChangeType:   "Modified",
-								Field:        "RestartCount",
-								OldValue:     oldContainer.RestartCount,
-								NewValue:     newContainer.RestartCount,
-								Timestamp:    time.Now(),
-							})
-						}
-					}
-				}
-
-				// Check for removed containers
-				for containerName := range oldPod.ContainerInfo {
-					if _, exists := newPod.ContainerInfo[containerName]; !exists {
-						changes = append(changes, ChangeEvent{
-							ResourceType: "Container",
-							ResourceName: fmt.Sprintf("%s/%s/%s", key, podName, containerName),
-							ChangeType:   "Removed",
-							Field:        "Status",
-							OldValue:     oldPod.ContainerInfo[containerName].Status,
-							Timestamp:    time.Now(),
-						})
-					}
-				}
-			}
-		}
+// CompareResource compares the new state of a resource with the cached state
+// using the comparator registered for its type, and caches the new state. A
+// nil Data means the resource was removed.
+func (sc *StateCache) CompareResource(resourceType, key string, newState ResourceState) []ChangeEvent {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
 
-		// Check for removed pods
-		for podName, oldPod := range oldData.Pods {
-			if _, exists := newData.Pods[podName]; !exists {
-				changes = append(changes, ChangeEvent{
-					ResourceType: "Pod",
-					ResourceName: fmt.Sprintf("%s/%s", key, podName),
-					ChangeType:   "Removed",
-					Field:        "Status",
-					OldValue:     oldPod.Status,
-					Timestamp:    time.Now(),
-				})
-			}
-		}
+	cacheKey := resourceCacheKey(resourceType, key)
+	var oldData interface{}
+	if oldState, exists := sc.cache[cacheKey]; exists {
+		oldData = oldState.Data
 	}
 
+	changes := sc.comparators.Diff(resourceType, key, oldData, newState.Data, func(resourceType, name string, state interface{}) {
+		// Keep the last state of removed nodes and pods for GetLastKnown
+		if resourceType == "Node" || resourceType == "Pod" {
+			sc.remember(name, state, time.Now())
+		}
+	})
+
 	// Update cache with new state if it's not a removal
 	if newState.Data != nil {
-		sc.cache[key] = newState
+		sc.cache[cacheKey] = newState
 	} else {
-		delete(sc.cache, key)
+		delete(sc.cache, cacheKey)
+	}
+
+	return changes
+}
+
+// CompareResources compares the current states of all resources of a type,
+// by name, with the cached states and caches them. Cached resources missing
+// from states were removed, which is reported if reportRemovals is set and
+// otherwise only forgets them, e.g. for events expiring in the cluster.
+func (sc *StateCache) CompareResources(resourceType string, states map[string]interface{}, reportRemovals bool) []ChangeEvent {
+	var changes []ChangeEvent
+	for _, name := range sortedKeys(states, nil) {
+		changes = append(changes, sc.CompareResource(resourceType, name, ResourceState{
+			Data:      states[name],
+			Timestamp: time.Now(),
+		})...)
+	}
+
+	sc.mu.Lock()
+	prefix := resourceCacheKey(resourceType, "")
+	var removed []string
+	for cacheKey := range sc.cache {
+		if name, ok := strings.CutPrefix(cacheKey, prefix); ok && resourceType != "Node" {
+			if _, exists := states[name]; !exists {
+				removed = append(removed, name)
+			}
+		}
 	}
+	if !reportRemovals {
+		for _, name := range removed {
+			delete(sc.cache, prefix+name)
+		}
+	}
+	sc.mu.Unlock()
 
+	if reportRemovals {
+		sort.Strings(removed)
+		for _, name := range removed {
+			changes = append(changes, sc.CompareResource(resourceType, name, ResourceState{Timestamp: time.Now()})...)
+		}
+	}
 	return changes
 }
+
+// Comparators returns the registry used to compare resources, for registering
+// comparators of other resource types
+func (sc *StateCache) Comparators() *ComparatorRegistry {
+	return sc.comparators
+}
+
+// resourceCacheKey returns the cache key of a resource. Nodes are cached by
+// name, other resource types are prefixed with their type.
+func resourceCacheKey(resourceType, key string) string {
+	if resourceType == "Node" {
+		return key
+	}
+	return resourceType + ":" + key
+}
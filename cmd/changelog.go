@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"time"
 
@@ -10,68 +9,145 @@ import (
 	"github.com/rivo/tview"
 )
 
+// DefaultChangeLogSize is the default number of change events kept in memory
+const DefaultChangeLogSize = 5000
+
+// historyMarker prefixes the time of entries reloaded from the log file
+const historyMarker = "↺ "
+
+// changeLogHeaders are the column titles of the change log table
+var changeLogHeaders = []string{"Time", "Resource", "Name", "Change", "Field", "Old Value", "New Value"}
+
+// changeLogEntry is a change event kept in the in-memory history
+type changeLogEntry struct {
+	change     ChangeEvent
+	historical bool // Reloaded from the log file at startup
+
+	// Repeated changes to the same resource field are coalesced into one
+	// entry, change is then the latest of count changes
+	count    int
+	first    time.Time   // Time of the first coalesced change
+	firstOld interface{} // Old value of the first coalesced change
+}
+
 // ChangeLogView represents the view for displaying change events
 type ChangeLogView struct {
-	table   *tview.Table
-	flex    *tview.Flex
-	app     *tview.Application
-	box     *tview.Box
-	logFile *os.File
+	table         *tview.Table
+	input         *tview.InputField
+	flex          *tview.Flex
+	app           *tview.Application
+	box           *tview.Box
+	logFile       *ChangeLogWriter
+	fileTitle     string           // Log file name and format shown in the title
+	entries       []changeLogEntry // Oldest first
+	maxEntries    int
+	reloaded      int // Entries reloaded from the log file
+	filter        changeLogFilter
+	editingFilter bool
+	doneFunc      func()
+	openFunc      func(change ChangeEvent, logs bool)
 }
 
-// NewChangeLogView creates a new ChangeLogView instance
-func NewChangeLogView(logFilePath string) *ChangeLogView {
+// NewChangeLogView creates a new ChangeLogView instance keeping up to maxEntries
+// change events, writing them to the log file described by fileOptions if it has a path
+func NewChangeLogView(fileOptions ChangeLogFileOptions, clusterName string, maxEntries int) *ChangeLogView {
+	if maxEntries <= 0 {
+		maxEntries = DefaultChangeLogSize
+	}
 	changeTable := tview.NewTable().
 		SetBorders(false).
+		SetFixed(1, 0).                                                  // Keep the headers visible while scrolling
 		SetSelectable(true, false).                                      // Make sure table is selectable
 		SetSelectedStyle(tcell.StyleDefault.Background(tcell.ColorNavy)) // Add visual feedback for focus
 
-	// Set up headers
-	headers := []string{"Time", "Resource", "Name", "Change", "Field", "Old Value", "New Value"}
-	for i, header := range headers {
-		cell := tview.NewTableCell(header).
-			SetTextColor(tcell.ColorWhite).
-			SetSelectable(false).
-			SetExpansion(1).
-			SetAttributes(tcell.AttrBold)
-		changeTable.SetCell(0, i, cell)
-	}
-
 	// Create a flex container
 	changeFlex := tview.NewFlex().
 		SetDirection(tview.FlexRow)
 
-	var logFile *os.File
-	if logFilePath != "" {
+	// Reload recent history before appending new events to the file
+	var history []ChangeRecord
+	if fileOptions.Path != "" && fileOptions.History > 0 {
 		var err error
-		logFile, err = os.OpenFile(logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		history, err = ReadChangeLogHistory(fileOptions, clusterName, fileOptions.History)
 		if err != nil {
-			fmt.Printf("Error opening log file: %v\n", err)
+			fmt.Printf("Error reading log file history: %v\n", err)
 		}
 	}
 
-	// Set border and title on the table itself
-	title := " Change Log "
-	if logFile != nil {
-		title = fmt.Sprintf(" Change Log [%s] ", filepath.Base(logFilePath))
+	var logFile *ChangeLogWriter
+	fileTitle := ""
+	if fileOptions.Path != "" {
+		var err error
+		logFile, err = NewChangeLogWriter(fileOptions, clusterName)
+		if err != nil {
+			fmt.Printf("Error opening log file: %v\n", err)
+		} else {
+			fileTitle = tview.Escape(fmt.Sprintf("[%s, %s]", filepath.Base(fileOptions.Path), logFile.Format()))
+		}
 	}
 
+	// Set border on the table itself, the title is set by updateTitle
 	changeTable.SetBorder(true).
 		SetBorderColor(tcell.ColorGray).
-		SetTitle(title).
 		SetBorderAttributes(tcell.AttrDim)
 
 	// Add the table to the flex with focus enabled
 	changeFlex.AddItem(changeTable, 0, 1, true)
 
 	cv := &ChangeLogView{
-		table:   changeTable,
-		flex:    changeFlex,
-		logFile: logFile,
+		table:      changeTable,
+		flex:       changeFlex,
+		logFile:    logFile,
+		fileTitle:  fileTitle,
+		maxEntries: maxEntries,
+		input: tview.NewInputField().
+			SetLabel("Filter: ").
+			SetFieldBackgroundColor(tcell.ColorBlack).
+			SetLabelColor(tcell.ColorYellow),
 	}
+	cv.input.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			if err := cv.SetFilter(cv.input.GetText()); err != nil {
+				cv.input.SetLabel(fmt.Sprintf("Filter (%s): ", tview.Escape(err.Error())))
+				return
+			}
+		}
+		cv.hideFilterInput()
+	})
+	changeTable.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			// Clear an active filter before leaving the view
+			if cv.filter.Active() {
+				cv.SetFilter("")
+				return nil
+			}
+			if cv.doneFunc != nil {
+				cv.doneFunc()
+			}
+			return nil
+		case tcell.KeyEnter:
+			if change, ok := cv.GetSelectedChange(); ok && cv.openFunc != nil {
+				cv.openFunc(change, false)
+			}
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case KeyChangeLogFilter:
+				cv.showFilterInput()
+				return nil
+			case KeyChangeLogLogs:
+				if change, ok := cv.GetSelectedChange(); ok && cv.openFunc != nil {
+					cv.openFunc(change, true)
+				}
+				return nil
+			}
+		}
+		return event
+	})
 
-	// Ensure the table starts with a selection
-	changeTable.Select(0, 0)
+	cv.AddHistory(history)
+	cv.render()
 
 	return cv
 }
@@ -93,6 +169,17 @@ func (cv *ChangeLogView) SetBox(box *tview.Box) {
 	cv.box = box
 }
 
+// SetDoneFunc sets the handler called when Esc is pressed without an active filter
+func (cv *ChangeLogView) SetDoneFunc(handler func()) {
+	cv.doneFunc = handler
+}
+
+// SetOpenFunc sets the handler called to open the resource of the selected
+// change with Enter, or its logs with the logs key
+func (cv *ChangeLogView) SetOpenFunc(handler func(change ChangeEvent, logs bool)) {
+	cv.openFunc = handler
+}
+
 // GetFlex returns the flex container
 func (cv *ChangeLogView) GetFlex() *tview.Flex {
 	return cv.flex
@@ -103,6 +190,67 @@ func (cv *ChangeLogView) GetTable() *tview.Table {
 	return cv.table
 }
 
+// IsEditingFilter reports whether the filter input has focus
+func (cv *ChangeLogView) IsEditingFilter() bool {
+	return cv.editingFilter
+}
+
+// GetSelectedChange returns the change event of the selected row
+func (cv *ChangeLogView) GetSelectedChange() (ChangeEvent, bool) {
+	row, _ := cv.table.GetSelection()
+	if row <= 0 || row >= cv.table.GetRowCount() {
+		return ChangeEvent{}, false
+	}
+	change, ok := cv.table.GetCell(row, 0).GetReference().(ChangeEvent)
+	return change, ok
+}
+
+// Changes returns the change events in the history, oldest first. Coalesced
+// flapping changes appear once, as the latest change.
+func (cv *ChangeLogView) Changes() []ChangeEvent {
+	changes := make([]ChangeEvent, len(cv.entries))
+	for i, entry := range cv.entries {
+		changes[i] = entry.change
+	}
+	return changes
+}
+
+// SetStatus shows a message in the title until the next update
+func (cv *ChangeLogView) SetStatus(status string) {
+	cv.updateTitle()
+	cv.table.SetTitle(cv.table.GetTitle() + "- " + status + " ")
+}
+
+// SetFilter shows only the entries matching the query, see parseChangeLogFilter
+func (cv *ChangeLogView) SetFilter(query string) error {
+	filter, err := parseChangeLogFilter(query)
+	if err != nil {
+		return err
+	}
+	cv.filter = filter
+	cv.render()
+	return nil
+}
+
+// showFilterInput adds the filter input below the table and focuses it
+func (cv *ChangeLogView) showFilterInput() {
+	cv.editingFilter = true
+	cv.input.SetLabel("Filter: ").SetText(cv.filter.query)
+	cv.flex.AddItem(cv.input, 1, 0, true)
+	if cv.app != nil {
+		cv.app.SetFocus(cv.input)
+	}
+}
+
+// hideFilterInput removes the filter input and returns focus to the table
+func (cv *ChangeLogView) hideFilterInput() {
+	cv.editingFilter = false
+	cv.flex.RemoveItem(cv.input)
+	if cv.app != nil {
+		cv.app.SetFocus(cv.table)
+	}
+}
+
 // flashTitle creates a flashing effect for the title
 func (cv *ChangeLogView) flashTitle() {
 	if cv.app == nil || cv.box == nil {
@@ -128,77 +276,152 @@ func (cv *ChangeLogView) flashTitle() {
 	}()
 }
 
-// AddChange adds a new change event to the log
-func (cv *ChangeLogView) AddChange(change ChangeEvent) {
-	// Format the row data
-	cells := []*tview.TableCell{
-		tview.NewTableCell(change.Timestamp.Format("2006-01-02 15:04:05")).SetTextColor(tcell.ColorWhite),
-		tview.NewTableCell(change.ResourceType).SetTextColor(tcell.ColorYellow),
-		tview.NewTableCell(change.ResourceName).SetTextColor(tcell.ColorAqua),
-		tview.NewTableCell(change.ChangeType).SetTextColor(func() tcell.Color {
-			switch change.ChangeType {
-			case "Added":
-				return tcell.ColorGreen
-			case "Removed":
-				return tcell.ColorRed
-			case "Modified":
-				return tcell.ColorYellow
-			default:
-				return tcell.ColorWhite
-			}
-		}()),
-		tview.NewTableCell(change.Field).SetTextColor(tcell.ColorSkyblue),
-		tview.NewTableCell(formatValue(change.OldValue)).SetTextColor(tcell.ColorGray),
-		tview.NewTableCell(formatValue(change.NewValue)).SetTextColor(tcell.ColorWhite),
+// AddHistory adds records reloaded from the log file before newer entries,
+// dimmed and marked as historical. They are not written to the file again.
+func (cv *ChangeLogView) AddHistory(records []ChangeRecord) {
+	if len(records) == 0 {
+		return
+	}
+	history := make([]changeLogEntry, 0, len(records)+len(cv.entries))
+	for _, record := range records {
+		change := record.ChangeEvent()
+		change.Timestamp = change.Timestamp.Local()
+		history = append(history, changeLogEntry{change: change, historical: true})
 	}
+	cv.entries = append(history, cv.entries...)
+	if len(cv.entries) > cv.maxEntries {
+		cv.entries = cv.entries[len(cv.entries)-cv.maxEntries:]
+	}
+	cv.reloaded = len(records)
+	cv.render()
+}
 
-	// Add the row to the table
-	cv.addRowReverseWithTruncate(cells, 20)
+// AddChange adds a new change event to the log. A change to the same resource
+// field as an entry changed within FlapWindow is coalesced into that entry,
+// which moves to the top; the log file still gets every change.
+func (cv *ChangeLogView) AddChange(change ChangeEvent) {
+	entry := changeLogEntry{change: change, count: 1, first: change.Timestamp, firstOld: change.OldValue}
+	if i := cv.findFlapping(change); i >= 0 {
+		previous := cv.entries[i]
+		entry.count = previous.count + 1
+		entry.first = previous.first
+		entry.firstOld = previous.firstOld
+		if cv.filter.Matches(previous.change) {
+			cv.removeRow(cv.rowOf(i))
+		}
+		cv.entries = append(cv.entries[:i], cv.entries[i+1:]...)
+	}
+	cv.entries = append(cv.entries, entry)
+
+	// Drop the oldest entry, and its row if it is shown, once the history is full
+	if len(cv.entries) > cv.maxEntries {
+		oldest := cv.entries[0]
+		cv.entries = cv.entries[1:]
+		if cv.filter.Matches(oldest.change) && cv.table.GetRowCount() > 1 {
+			cv.table.RemoveRow(cv.table.GetRowCount() - 1)
+		}
+	}
 
-	// Optional: Ensure focus stays at the top
-	cv.table.Select(1, 0)
+	if cv.filter.Matches(change) {
+		cv.insertRow(entry.cells())
+	}
+	cv.updateTitle()
 
 	// Write to log file if enabled
 	if cv.logFile != nil {
-		logEntry := fmt.Sprintf("[%s] %s %s %s\n",
-			change.Timestamp.Format("2006-01-02 15:04:05"),
-			change.ResourceType,
-			change.ResourceName,
-			change.ChangeType)
-
-		if _, err := cv.logFile.WriteString(logEntry); err != nil {
-			fmt.Printf("Error writing to log file: %v\n", err)
+		if err := cv.logFile.Write(change); err != nil {
+			// Shown in the title, printing would corrupt the screen owned by the UI
+			cv.SetStatus(fmt.Sprintf("[red]Error writing to log file: %s[-]", tview.Escape(err.Error())))
 		}
-		// Flush immediately
-		cv.logFile.Sync()
 	}
 
 	// Trigger title flash
 	cv.flashTitle()
 }
 
-// formatValue formats a value for display in the changelog
-func formatValue(value interface{}) string {
-	if value == nil {
-		return "-"
+// findFlapping returns the index of the entry a change is coalesced into, or -1
+func (cv *ChangeLogView) findFlapping(change ChangeEvent) int {
+	key, ok := newFlapKey(change)
+	if !ok {
+		return -1
+	}
+	for i := len(cv.entries) - 1; i >= 0; i-- {
+		entry := cv.entries[i]
+		if change.Timestamp.Sub(entry.change.Timestamp) > FlapWindow {
+			break
+		}
+		if entryKey, ok := newFlapKey(entry.change); ok && entryKey == key && !entry.historical {
+			return i
+		}
 	}
+	return -1
+}
 
-	// If it's a NodeData struct, just return "present" or a simplified representation
-	if _, ok := value.(NodeData); ok {
-		return "present"
+// Flapping returns the latest change of each resource field that changed at
+// least FlapThreshold times, most recently within FlapWindow before now
+func (cv *ChangeLogView) Flapping(now time.Time) []ChangeEvent {
+	var changes []ChangeEvent
+	for i := len(cv.entries) - 1; i >= 0; i-- {
+		entry := cv.entries[i]
+		if now.Sub(entry.change.Timestamp) > FlapWindow {
+			break
+		}
+		if entry.count >= FlapThreshold {
+			changes = append(changes, entry.change)
+		}
 	}
+	return changes
+}
 
-	// For other types, return a simple string representation
-	return fmt.Sprintf("%v", value)
+// rowOf returns the table row of a shown entry, newer entries are above it
+func (cv *ChangeLogView) rowOf(index int) int {
+	row := 1
+	for _, entry := range cv.entries[index+1:] {
+		if cv.filter.Matches(entry.change) {
+			row++
+		}
+	}
+	return row
 }
 
-// Clear clears all entries from the change log
-func (cv *ChangeLogView) Clear() {
-	cv.table.Clear()
+// removeRow removes a row, keeping the selected entry and visible entries in place
+func (cv *ChangeLogView) removeRow(row int) {
+	selected, _ := cv.table.GetSelection()
+	offset, _ := cv.table.GetOffset()
+	cv.table.RemoveRow(row)
+	if row < selected {
+		cv.table.Select(selected-1, 0)
+	}
+	if row <= offset && offset > 0 {
+		cv.table.SetOffset(offset-1, 0)
+	}
+}
+
+// insertRow adds a row for the newest entry at the top. When the user has
+// scrolled down or selected an older entry, the selection and scroll position
+// move along so the visible entries don't jump.
+func (cv *ChangeLogView) insertRow(cells []*tview.TableCell) {
+	row, _ := cv.table.GetSelection()
+	offset, _ := cv.table.GetOffset()
+	following := row <= 1 && offset == 0
+
+	cv.table.InsertRow(1)
+	for col, cell := range cells {
+		cv.table.SetCell(1, col, cell)
+	}
+
+	if following {
+		cv.table.Select(1, 0)
+	} else {
+		cv.table.Select(row+1, 0)
+		cv.table.SetOffset(offset+1, 0)
+	}
+}
 
-	// Restore headers
-	headers := []string{"Time", "Resource", "Name", "Change", "Field", "Old Value", "New Value"}
-	for i, header := range headers {
+// render redraws the table from the history with the filter applied, newest first
+func (cv *ChangeLogView) render() {
+	cv.table.Clear()
+	for i, header := range changeLogHeaders {
 		cell := tview.NewTableCell(header).
 			SetTextColor(tcell.ColorWhite).
 			SetSelectable(false).
@@ -207,30 +430,99 @@ func (cv *ChangeLogView) Clear() {
 		cv.table.SetCell(0, i, cell)
 	}
 
-	// Ensure selection is maintained after clearing
-	cv.table.Select(0, 0)
+	row := 1
+	for i := len(cv.entries) - 1; i >= 0; i-- {
+		if !cv.filter.Matches(cv.entries[i].change) {
+			continue
+		}
+		for col, cell := range cv.entries[i].cells() {
+			cv.table.SetCell(row, col, cell)
+		}
+		row++
+	}
+
+	cv.table.SetOffset(0, 0)
+	cv.table.Select(1, 0)
+	cv.updateTitle()
 }
 
-func (cv *ChangeLogView) addRowReverseWithTruncate(cells []*tview.TableCell, maxRows int) {
-	rowCount := cv.table.GetRowCount()
-	for row := rowCount - 1; row > 0; row-- { // Shift existing rows down
-		for col := 0; col < cv.table.GetColumnCount(); col++ {
-			cell := cv.table.GetCell(row, col)
-			if cell != nil {
-				cv.table.SetCell(row+1, col, cell)
-			}
+// updateTitle shows the log file, reloaded entries and active filter in the title
+func (cv *ChangeLogView) updateTitle() {
+	title := " Change Log "
+	if cv.fileTitle != "" {
+		title += cv.fileTitle + " "
+	}
+	if cv.reloaded > 0 {
+		title += fmt.Sprintf("- %s%d entries reloaded ", historyMarker, cv.reloaded)
+	}
+	if cv.filter.Active() {
+		title += fmt.Sprintf("- [yellow]filter: %s (%d of %d)[-] ", tview.Escape(cv.filter.query), cv.table.GetRowCount()-1, len(cv.entries))
+	} else if len(cv.entries) > 0 {
+		title += fmt.Sprintf("- %d ", len(cv.entries))
+	}
+	cv.table.SetTitle(title)
+}
+
+// cells formats the entry as a table row, dimming historical entries
+func (e changeLogEntry) cells() []*tview.TableCell {
+	cells := changeCells(e.change)
+	cells[0].SetReference(e.change)
+	if e.count > 1 {
+		cells[3].SetText(fmt.Sprintf("%s flapped %d× in %s", tview.Escape(e.change.Field), e.count, formatFlapDuration(e.change.Timestamp.Sub(e.first)))).
+			SetTextColor(tcell.ColorFuchsia)
+		cells[5].SetText(tview.Escape(formatValue(e.firstOld)))
+	}
+	if e.historical {
+		cells[0].SetText(historyMarker + cells[0].Text)
+		for _, cell := range cells {
+			cell.SetTextColor(tcell.ColorGray).SetAttributes(tcell.AttrDim)
 		}
 	}
+	return cells
+}
 
-	// Insert the new row at the top
-	for col, cell := range cells {
-		cv.table.SetCell(1, col, cell)
+// changeCells formats a change event as a table row
+func changeCells(change ChangeEvent) []*tview.TableCell {
+	return []*tview.TableCell{
+		tview.NewTableCell(change.Timestamp.Format("2006-01-02 15:04:05")).SetTextColor(tcell.ColorWhite),
+		tview.NewTableCell(change.ResourceType).SetTextColor(tcell.ColorYellow),
+		tview.NewTableCell(change.ResourceName).SetTextColor(tcell.ColorAqua),
+		tview.NewTableCell(change.ChangeType).SetTextColor(func() tcell.Color {
+			switch change.ChangeType {
+			case "Added":
+				return tcell.ColorGreen
+			case "Removed":
+				return tcell.ColorRed
+			case "Modified":
+				return tcell.ColorYellow
+			default:
+				return tcell.ColorWhite
+			}
+		}()),
+		tview.NewTableCell(tview.Escape(change.Field)).SetTextColor(tcell.ColorSkyblue),
+		tview.NewTableCell(tview.Escape(formatValue(change.OldValue))).SetTextColor(tcell.ColorGray),
+		tview.NewTableCell(tview.Escape(formatValue(change.NewValue))).SetTextColor(tcell.ColorWhite),
 	}
+}
 
-	// Truncate rows if exceeding maxRows
-	if rowCount >= maxRows {
-		cv.table.RemoveRow(rowCount)
+// formatValue formats a value for display in the changelog
+func formatValue(value interface{}) string {
+	if value == nil {
+		return "-"
 	}
 
-	cv.table.ScrollToBeginning()
+	// If it's a NodeData struct, just return "present" or a simplified representation
+	if _, ok := value.(NodeData); ok {
+		return "present"
+	}
+
+	// For other types, return a simple string representation
+	return fmt.Sprintf("%v", value)
+}
+
+// Clear clears all entries from the change log
+func (cv *ChangeLogView) Clear() {
+	cv.entries = nil
+	cv.reloaded = 0
+	cv.render()
 }
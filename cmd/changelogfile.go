@@ -0,0 +1,489 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Change log file formats
+const (
+	ChangeLogFormatText  = "text"
+	ChangeLogFormatJSONL = "jsonl"
+	ChangeLogFormatCSV   = "csv"
+)
+
+// changeLogCSVHeader is the first row of CSV change log files
+var changeLogCSVHeader = []string{"time", "cluster", "namespace", "resource_type", "resource_name", "change_type", "field", "old_value", "new_value"}
+
+// DefaultChangeLogHistory is the default number of entries reloaded from the change log file at startup
+const DefaultChangeLogHistory = 100
+
+// changeLogRotationLayout is the timestamp added to the names of rotated files
+const changeLogRotationLayout = "20060102T150405"
+
+// ChangeLogFileOptions configures the change log file
+type ChangeLogFileOptions struct {
+	Path           string
+	Format         string        // text, jsonl or csv, derived from the file extension if empty
+	MaxSize        int64         // Rotate once the file reaches this many bytes, zero to disable
+	RotateInterval time.Duration // Rotate when a new interval starts, e.g. daily, zero to disable
+	MaxBackups     int           // Number of rotated files kept, zero to keep all
+	MaxAge         time.Duration // Rotated files older than this are deleted, zero to keep all
+	History        int           // Most recent entries reloaded from the file at startup, zero to disable
+}
+
+// ChangeRecord is a change event as written to the change log file
+type ChangeRecord struct {
+	Time         time.Time `json:"time"`
+	Cluster      string    `json:"cluster"`
+	Namespace    string    `json:"namespace,omitempty"`
+	ResourceType string    `json:"resourceType"`
+	ResourceName string    `json:"resourceName"`
+	ChangeType   string    `json:"changeType"`
+	Field        string    `json:"field,omitempty"`
+	OldValue     string    `json:"oldValue,omitempty"`
+	NewValue     string    `json:"newValue,omitempty"`
+}
+
+// NewChangeRecord converts a change event for writing to the change log file
+func NewChangeRecord(cluster string, change ChangeEvent) ChangeRecord {
+	record := ChangeRecord{
+		Time:         change.Timestamp,
+		Cluster:      cluster,
+		Namespace:    change.Namespace,
+		ResourceType: change.ResourceType,
+		ResourceName: change.ResourceName,
+		ChangeType:   change.ChangeType,
+		Field:        change.Field,
+	}
+	if change.OldValue != nil {
+		record.OldValue = formatValue(change.OldValue)
+	}
+	if change.NewValue != nil {
+		record.NewValue = formatValue(change.NewValue)
+	}
+	return record
+}
+
+// csvRow returns the record as a CSV row in the order of changeLogCSVHeader
+func (r ChangeRecord) csvRow() []string {
+	return []string{
+		r.Time.Format(time.RFC3339),
+		r.Cluster,
+		r.Namespace,
+		r.ResourceType,
+		r.ResourceName,
+		r.ChangeType,
+		r.Field,
+		r.OldValue,
+		r.NewValue,
+	}
+}
+
+// text formats the record as a human-readable line
+func (r ChangeRecord) text() string {
+	line := fmt.Sprintf("[%s] %s %s %s", r.Time.Format("2006-01-02 15:04:05"), r.ResourceType, r.ResourceName, r.ChangeType)
+	if r.Field != "" {
+		line += " " + r.Field
+	}
+	if r.OldValue != "" || r.NewValue != "" {
+		line += fmt.Sprintf(": %s -> %s", valueOrDash(r.OldValue), valueOrDash(r.NewValue))
+	}
+	line += " (cluster=" + r.Cluster
+	if r.Namespace != "" {
+		line += " namespace=" + r.Namespace
+	}
+	return line + ")"
+}
+
+// valueOrDash returns "-" for empty values
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// ChangeLogFormatFromPath picks the format matching the file extension, defaulting to text
+func ChangeLogFormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson", ".json":
+		return ChangeLogFormatJSONL
+	case ".csv":
+		return ChangeLogFormatCSV
+	}
+	return ChangeLogFormatText
+}
+
+// ChangeLogWriter appends change events to a file in the chosen format and
+// rotates it by size or time
+type ChangeLogWriter struct {
+	mu        sync.Mutex
+	options   ChangeLogFileOptions
+	cluster   string
+	file      *os.File
+	size      int64
+	lastWrite time.Time
+}
+
+// NewChangeLogWriter opens the change log file for appending
+func NewChangeLogWriter(options ChangeLogFileOptions, cluster string) (*ChangeLogWriter, error) {
+	if options.Format == "" {
+		options.Format = ChangeLogFormatFromPath(options.Path)
+	}
+	switch options.Format {
+	case ChangeLogFormatText, ChangeLogFormatJSONL, ChangeLogFormatCSV:
+	default:
+		return nil, fmt.Errorf("unknown change log format %q (expected text, jsonl or csv)", options.Format)
+	}
+
+	w := &ChangeLogWriter{options: options, cluster: cluster}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Format returns the format records are written in
+func (w *ChangeLogWriter) Format() string {
+	return w.options.Format
+}
+
+// open opens the file for appending and writes the CSV header to new files
+func (w *ChangeLogWriter) open() error {
+	file, err := os.OpenFile(w.options.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file = file
+	w.size = info.Size()
+	w.lastWrite = info.ModTime()
+
+	if w.size == 0 && w.options.Format == ChangeLogFormatCSV {
+		return w.writeCSV(changeLogCSVHeader)
+	}
+	return nil
+}
+
+// Write appends a change event, rotating the file first if needed
+func (w *ChangeLogWriter) Write(change ChangeEvent) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return fmt.Errorf("change log file is closed")
+	}
+
+	now := time.Now()
+	if w.shouldRotate(now) {
+		if err := w.rotate(now); err != nil {
+			return fmt.Errorf("failed to rotate change log: %v", err)
+		}
+	}
+
+	record := NewChangeRecord(w.cluster, change)
+	var err error
+	switch w.options.Format {
+	case ChangeLogFormatJSONL:
+		var data []byte
+		data, err = json.Marshal(record)
+		if err == nil {
+			err = w.writeString(string(data) + "\n")
+		}
+	case ChangeLogFormatCSV:
+		err = w.writeCSV(record.csvRow())
+	default:
+		err = w.writeString(record.text() + "\n")
+	}
+	if err != nil {
+		return err
+	}
+	w.lastWrite = now
+
+	// Flush immediately
+	return w.file.Sync()
+}
+
+// writeString writes to the file and tracks its size
+func (w *ChangeLogWriter) writeString(s string) error {
+	n, err := w.file.WriteString(s)
+	w.size += int64(n)
+	return err
+}
+
+// writeCSV writes one CSV row
+func (w *ChangeLogWriter) writeCSV(row []string) error {
+	var sb strings.Builder
+	writer := csv.NewWriter(&sb)
+	if err := writer.Write(row); err != nil {
+		return err
+	}
+	writer.Flush()
+	return w.writeString(sb.String())
+}
+
+// shouldRotate reports whether the size limit is reached or a new interval started
+func (w *ChangeLogWriter) shouldRotate(now time.Time) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.options.MaxSize > 0 && w.size >= w.options.MaxSize {
+		return true
+	}
+	if w.options.RotateInterval > 0 && !w.lastWrite.IsZero() &&
+		!now.Truncate(w.options.RotateInterval).Equal(w.lastWrite.Truncate(w.options.RotateInterval)) {
+		return true
+	}
+	return false
+}
+
+// rotatedChangeLogFiles returns the rotated files of a change log, oldest
+// first. Only names with a rotation timestamp and optional .N suffix between
+// the base name and extension match, so unrelated files sharing the prefix,
+// such as changes-prod.jsonl next to changes.jsonl, are never touched.
+func rotatedChangeLogFiles(path string) ([]string, error) {
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(path, ext) + "-"
+	matches, err := filepath.Glob(globEscape(prefix) + "*" + globEscape(ext))
+	if err != nil {
+		return nil, err
+	}
+
+	type rotatedFile struct {
+		path string
+		time time.Time
+		n    int
+	}
+	var files []rotatedFile
+	for _, match := range matches {
+		middle := strings.TrimSuffix(strings.TrimPrefix(match, prefix), ext)
+		stamp, suffix, _ := strings.Cut(middle, ".")
+		rotatedAt, err := time.ParseInLocation(changeLogRotationLayout, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		n := 0
+		if suffix != "" {
+			if n, err = strconv.Atoi(suffix); err != nil || n < 1 || strconv.Itoa(n) != suffix {
+				continue
+			}
+		}
+		files = append(files, rotatedFile{path: match, time: rotatedAt, n: n})
+	}
+
+	// Files rotated within the same second are numbered in order
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].time.Equal(files[j].time) {
+			return files[i].time.Before(files[j].time)
+		}
+		return files[i].n < files[j].n
+	})
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.path
+	}
+	return paths, nil
+}
+
+// globEscape escapes the glob metacharacters of a literal path
+func globEscape(path string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`).Replace(path)
+}
+
+// rotate renames the current file with a timestamp, opens a new one and
+// removes rotated files beyond the retention limits
+func (w *ChangeLogWriter) rotate(now time.Time) error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	ext := filepath.Ext(w.options.Path)
+	base := fmt.Sprintf("%s-%s", strings.TrimSuffix(w.options.Path, ext), now.Format(changeLogRotationLayout))
+	rotated := base + ext
+	for i := 1; ; i++ {
+		// Never overwrite a file rotated within the same second
+		if _, err := os.Stat(rotated); os.IsNotExist(err) {
+			break
+		}
+		rotated = fmt.Sprintf("%s.%d%s", base, i, ext)
+	}
+	if err := os.Rename(w.options.Path, rotated); err != nil {
+		// Keep appending to the current file rather than losing events
+		if openErr := w.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.prune(now)
+	return nil
+}
+
+// prune deletes rotated files beyond MaxBackups or older than MaxAge
+func (w *ChangeLogWriter) prune(now time.Time) {
+	if w.options.MaxBackups <= 0 && w.options.MaxAge <= 0 {
+		return
+	}
+	rotated, err := rotatedChangeLogFiles(w.options.Path)
+	if err != nil {
+		return
+	}
+
+	// Walk from the newest rotated file to the oldest
+	for i := range rotated {
+		path := rotated[len(rotated)-1-i]
+		expired := false
+		if w.options.MaxBackups > 0 && i >= w.options.MaxBackups {
+			expired = true
+		}
+		if w.options.MaxAge > 0 {
+			if info, err := os.Stat(path); err == nil && now.Sub(info.ModTime()) > w.options.MaxAge {
+				expired = true
+			}
+		}
+		if expired {
+			os.Remove(path)
+		}
+	}
+}
+
+// Close closes the change log file
+func (w *ChangeLogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// ReadChangeLogHistory returns up to limit of the most recent records of a
+// JSONL or CSV change log, oldest first, reading rotated files if the current
+// one holds fewer. Records of other clusters are skipped. Text logs can't be
+// read back and return no records.
+func ReadChangeLogHistory(options ChangeLogFileOptions, cluster string, limit int) ([]ChangeRecord, error) {
+	if options.Format == "" {
+		options.Format = ChangeLogFormatFromPath(options.Path)
+	}
+	if limit <= 0 || options.Format == ChangeLogFormatText {
+		return nil, nil
+	}
+
+	rotated, err := rotatedChangeLogFiles(options.Path)
+	if err != nil {
+		return nil, err
+	}
+	paths := append(rotated, options.Path)
+
+	// Read from the newest file backwards until enough records are found
+	var records []ChangeRecord
+	for i := len(paths) - 1; i >= 0 && len(records) < limit; i-- {
+		fileRecords, err := readChangeLogFile(paths[i], options.Format)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var matching []ChangeRecord
+		for _, record := range fileRecords {
+			if record.Cluster == "" || record.Cluster == cluster {
+				matching = append(matching, record)
+			}
+		}
+		records = append(matching, records...)
+	}
+	if len(records) > limit {
+		records = records[len(records)-limit:]
+	}
+	return records, nil
+}
+
+// readChangeLogFile parses all records of a JSONL or CSV change log file,
+// skipping lines that can't be parsed such as a partially written last line
+func readChangeLogFile(path, format string) ([]ChangeRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []ChangeRecord
+	if format == ChangeLogFormatJSONL {
+		for _, line := range strings.Split(string(data), "\n") {
+			var record ChangeRecord
+			if strings.TrimSpace(line) == "" || json.Unmarshal([]byte(line), &record) != nil {
+				continue
+			}
+			records = append(records, record)
+		}
+		return records, nil
+	}
+
+	reader := csv.NewReader(strings.NewReader(string(data)))
+	reader.FieldsPerRecord = -1
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		if len(row) < len(changeLogCSVHeader) || row[0] == changeLogCSVHeader[0] {
+			continue
+		}
+		timestamp, err := time.Parse(time.RFC3339, row[0])
+		if err != nil {
+			continue
+		}
+		records = append(records, ChangeRecord{
+			Time:         timestamp,
+			Cluster:      row[1],
+			Namespace:    row[2],
+			ResourceType: row[3],
+			ResourceName: row[4],
+			ChangeType:   row[5],
+			Field:        row[6],
+			OldValue:     row[7],
+			NewValue:     row[8],
+		})
+	}
+	return records, nil
+}
+
+// ChangeEvent converts a record read back from the change log file
+func (r ChangeRecord) ChangeEvent() ChangeEvent {
+	change := ChangeEvent{
+		ResourceType: r.ResourceType,
+		ResourceName: r.ResourceName,
+		Namespace:    r.Namespace,
+		ChangeType:   r.ChangeType,
+		Field:        r.Field,
+		Timestamp:    r.Time,
+	}
+	if r.OldValue != "" {
+		change.OldValue = r.OldValue
+	}
+	if r.NewValue != "" {
+		change.NewValue = r.NewValue
+	}
+	return change
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// changeLogFilter selects the change log entries that are shown. Empty
+// criteria match everything; lists match any of their values.
+type changeLogFilter struct {
+	query         string   // Query the filter was parsed from
+	resourceTypes []string // e.g. Pod, Container
+	changeTypes   []string // e.g. Modified
+	fields        []string // Matched as substrings, e.g. Restart
+	namespaces    []string
+	text          []string // Every word must appear in the name, field or values
+}
+
+// changeLogFilterKeys maps the prefixes accepted in filter queries to the criteria they set
+var changeLogFilterKeys = map[string]string{
+	"resource":  "resource",
+	"type":      "resource",
+	"kind":      "resource",
+	"change":    "change",
+	"field":     "field",
+	"ns":        "namespace",
+	"namespace": "namespace",
+}
+
+// parseChangeLogFilter parses a query such as "type:Pod,Container change:Modified
+// ns:payments crash". Words without a known prefix are matched as free text.
+func parseChangeLogFilter(query string) (changeLogFilter, error) {
+	filter := changeLogFilter{query: strings.TrimSpace(query)}
+	for _, word := range strings.Fields(query) {
+		key, value, found := strings.Cut(word, ":")
+		criterion, known := changeLogFilterKeys[strings.ToLower(key)]
+		if !found || !known {
+			filter.text = append(filter.text, strings.ToLower(word))
+			continue
+		}
+		if value == "" {
+			return changeLogFilter{}, fmt.Errorf("missing value for %s:", key)
+		}
+		values := strings.Split(strings.ToLower(value), ",")
+		switch criterion {
+		case "resource":
+			filter.resourceTypes = append(filter.resourceTypes, values...)
+		case "change":
+			filter.changeTypes = append(filter.changeTypes, values...)
+		case "field":
+			filter.fields = append(filter.fields, values...)
+		case "namespace":
+			filter.namespaces = append(filter.namespaces, values...)
+		}
+	}
+	return filter, nil
+}
+
+// Active reports whether the filter hides any entries
+func (f changeLogFilter) Active() bool {
+	return f.query != ""
+}
+
+// Matches reports whether a change passes the filter
+func (f changeLogFilter) Matches(change ChangeEvent) bool {
+	if !matchesAny(f.resourceTypes, change.ResourceType, strings.EqualFold) ||
+		!matchesAny(f.changeTypes, change.ChangeType, strings.EqualFold) ||
+		!matchesAny(f.namespaces, change.Namespace, strings.EqualFold) ||
+		!matchesAny(f.fields, strings.ToLower(change.Field), func(field, value string) bool {
+			return strings.Contains(field, value)
+		}) {
+		return false
+	}
+
+	if len(f.text) > 0 {
+		haystack := strings.ToLower(strings.Join([]string{
+			change.ResourceType, change.ResourceName, change.Namespace, change.ChangeType,
+			change.Field, formatValue(change.OldValue), formatValue(change.NewValue),
+		}, " "))
+		for _, word := range f.text {
+			if !strings.Contains(haystack, word) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchesAny reports whether value matches one of the wanted values, or true if none are wanted
+func matchesAny(wanted []string, value string, match func(value, wanted string) bool) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	for _, w := range wanted {
+		if match(value, w) {
+			return true
+		}
+	}
+	return false
+}
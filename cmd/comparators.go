@@ -0,0 +1,317 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FieldChange is a change of one watched field of a resource
+type FieldChange struct {
+	Field    string
+	OldValue interface{}
+	NewValue interface{}
+}
+
+// Comparator detects the changes of one resource type. Resources can nest
+// others, such as the pods of a node, which are compared by the comparators
+// registered for their own type.
+type Comparator interface {
+	// Describe returns the namespace of a state, empty to use the parent's, and
+	// the field and value recorded when the resource is added or removed
+	Describe(state interface{}) (namespace, field string, value interface{})
+
+	// Compare returns the watched fields that differ between two states
+	Compare(old, new interface{}) []FieldChange
+
+	// Children returns the nested resources of a state by resource type and name
+	Children(state interface{}) map[string]map[string]interface{}
+}
+
+// ComparatorRegistry holds the comparators by resource type and produces the
+// change events between two states of a resource
+type ComparatorRegistry struct {
+	comparators map[string]Comparator
+}
+
+// NewComparatorRegistry creates a registry with the built-in comparators for
+// nodes, pods, containers, workloads and events
+func NewComparatorRegistry() *ComparatorRegistry {
+	r := &ComparatorRegistry{comparators: make(map[string]Comparator)}
+	r.Register("Node", nodeComparator{})
+	r.Register("Pod", podComparator{})
+	r.Register("Container", containerComparator{})
+	r.Register("Workload", workloadComparator{})
+	r.Register("Event", eventComparator{})
+	return r
+}
+
+// Register sets the comparator of a resource type, replacing any existing one
+func (r *ComparatorRegistry) Register(resourceType string, comparator Comparator) {
+	r.comparators[resourceType] = comparator
+}
+
+// Get returns the comparator of a resource type
+func (r *ComparatorRegistry) Get(resourceType string) (Comparator, bool) {
+	comparator, ok := r.comparators[resourceType]
+	return comparator, ok
+}
+
+// Diff returns the changes between the old and new state of a resource, nil
+// meaning it doesn't exist, including the changes of its nested resources.
+// Nested resources are named after their parent, e.g. "node/pod". removed, if
+// set, is called with the last state of every removed resource.
+func (r *ComparatorRegistry) Diff(resourceType, name string, old, new interface{}, removed func(resourceType, name string, state interface{})) []ChangeEvent {
+	var changes []ChangeEvent
+	r.diff(resourceType, name, "", old, new, time.Now(), removed, &changes)
+	return changes
+}
+
+// diff appends the changes of one resource and its nested resources
+func (r *ComparatorRegistry) diff(resourceType, name, parentNamespace string, old, new interface{}, now time.Time, removed func(string, string, interface{}), changes *[]ChangeEvent) {
+	comparator, ok := r.comparators[resourceType]
+	if !ok || (old == nil && new == nil) {
+		return
+	}
+
+	change := func(changeType, namespace string, field FieldChange) ChangeEvent {
+		if namespace == "" {
+			namespace = parentNamespace
+		}
+		return ChangeEvent{
+			ResourceType: resourceType,
+			ResourceName: name,
+			Namespace:    namespace,
+			ChangeType:   changeType,
+			Field:        field.Field,
+			OldValue:     field.OldValue,
+			NewValue:     field.NewValue,
+			Timestamp:    now,
+		}
+	}
+
+	switch {
+	case old == nil:
+		namespace, field, value := comparator.Describe(new)
+		*changes = append(*changes, change("Added", namespace, FieldChange{Field: field, NewValue: value}))
+		return
+	case new == nil:
+		if removed != nil {
+			removed(resourceType, name, old)
+		}
+		namespace, field, value := comparator.Describe(old)
+		*changes = append(*changes, change("Removed", namespace, FieldChange{Field: field, OldValue: value}))
+		return
+	}
+
+	namespace, _, _ := comparator.Describe(new)
+	for _, field := range comparator.Compare(old, new) {
+		*changes = append(*changes, change("Modified", namespace, field))
+	}
+	if namespace == "" {
+		namespace = parentNamespace
+	}
+
+	oldChildren, newChildren := comparator.Children(old), comparator.Children(new)
+	for _, childType := range sortedKeys(oldChildren, newChildren) {
+		for _, childName := range sortedKeys(oldChildren[childType], newChildren[childType]) {
+			r.diff(childType, name+"/"+childName, namespace,
+				oldChildren[childType][childName], newChildren[childType][childName], now, removed, changes)
+		}
+	}
+}
+
+// sortedKeys returns the keys of both maps in order
+func sortedKeys[V any](a, b map[string]V) []string {
+	var keys []string
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// changedFields returns the fields whose old and new values differ
+func changedFields(fields ...FieldChange) []FieldChange {
+	var changed []FieldChange
+	for _, field := range fields {
+		if !reflect.DeepEqual(field.OldValue, field.NewValue) {
+			changed = append(changed, field)
+		}
+	}
+	return changed
+}
+
+// mapChanges returns a change for every added, removed or modified key, named
+// e.g. Label[app]. Added keys have a nil old value and removed keys a nil new value.
+func mapChanges(field string, old, new map[string]string) []FieldChange {
+	var changes []FieldChange
+	for _, key := range sortedKeys(old, new) {
+		changes = append(changes, changedFields(FieldChange{
+			fmt.Sprintf("%s[%s]", field, key), mapValue(old, key), mapValue(new, key),
+		})...)
+	}
+	return changes
+}
+
+// mapValue returns the value of key, or nil if it's missing
+func mapValue(m map[string]string, key string) interface{} {
+	if value, ok := m[key]; ok {
+		return value
+	}
+	return nil
+}
+
+// nodeComparator compares NodeData, nesting its pods
+type nodeComparator struct{}
+
+func (nodeComparator) Describe(state interface{}) (string, string, interface{}) {
+	return "", "", state
+}
+
+func (nodeComparator) Compare(old, new interface{}) []FieldChange {
+	o, _ := old.(NodeData)
+	n, _ := new.(NodeData)
+	changes := changedFields(
+		FieldChange{"Status", o.Status, n.Status},
+		FieldChange{"Version", o.Version, n.Version},
+		FieldChange{"PodCount", o.PodCount, n.PodCount},
+	)
+	for _, condition := range WatchedNodeConditions {
+		changes = append(changes, changedFields(FieldChange{string(condition),
+			mapValue(o.Conditions, string(condition)), mapValue(n.Conditions, string(condition))})...)
+	}
+	changes = append(changes, changedFields(FieldChange{"Unschedulable", o.Unschedulable, n.Unschedulable})...)
+	changes = append(changes, mapChanges("Taint", o.Taints, n.Taints)...)
+	changes = append(changes, mapChanges("Label", o.Labels, n.Labels)...)
+	changes = append(changes, mapChanges("Annotation", o.Annotations, n.Annotations)...)
+	changes = append(changes, mapChanges("Allocatable", o.Allocatable, n.Allocatable)...)
+	return changes
+}
+
+func (nodeComparator) Children(state interface{}) map[string]map[string]interface{} {
+	data, _ := state.(NodeData)
+	pods := make(map[string]interface{}, len(data.Pods))
+	for name, pod := range data.Pods {
+		pods[name] = pod
+	}
+	return map[string]map[string]interface{}{"Pod": pods}
+}
+
+// podComparator compares PodInfo, nesting its containers
+type podComparator struct{}
+
+func (podComparator) Describe(state interface{}) (string, string, interface{}) {
+	pod, _ := state.(PodInfo)
+	return pod.Namespace, "Status", pod.Status
+}
+
+func (podComparator) Compare(old, new interface{}) []FieldChange {
+	o, _ := old.(PodInfo)
+	n, _ := new.(PodInfo)
+	return changedFields(
+		FieldChange{"Status", o.Status, n.Status},
+		FieldChange{"RestartCount", o.RestartCount, n.RestartCount},
+	)
+}
+
+func (podComparator) Children(state interface{}) map[string]map[string]interface{} {
+	pod, _ := state.(PodInfo)
+	containers := make(map[string]interface{}, len(pod.ContainerInfo))
+	for name, container := range pod.ContainerInfo {
+		containers[name] = container
+	}
+	return map[string]map[string]interface{}{"Container": containers}
+}
+
+// containerComparator compares ContainerInfo
+type containerComparator struct{}
+
+func (containerComparator) Describe(state interface{}) (string, string, interface{}) {
+	container, _ := state.(ContainerInfo)
+	return "", "Status", container.Status
+}
+
+func (containerComparator) Compare(old, new interface{}) []FieldChange {
+	o, _ := old.(ContainerInfo)
+	n, _ := new.(ContainerInfo)
+	return changedFields(
+		FieldChange{"Status", o.Status, n.Status},
+		FieldChange{"RestartCount", o.RestartCount, n.RestartCount},
+	)
+}
+
+func (containerComparator) Children(interface{}) map[string]map[string]interface{} {
+	return nil
+}
+
+// WorkloadInfo is the watched state of a Deployment, StatefulSet or DaemonSet
+type WorkloadInfo struct {
+	Kind            string
+	Namespace       string
+	Replicas        int32 // Desired replicas, or scheduled pods of a DaemonSet
+	ReadyReplicas   int32
+	UpdatedReplicas int32
+	Images          []string // Container images of the pod template
+}
+
+// workloadComparator compares WorkloadInfo
+type workloadComparator struct{}
+
+func (workloadComparator) Describe(state interface{}) (string, string, interface{}) {
+	workload, _ := state.(WorkloadInfo)
+	return workload.Namespace, "Kind", workload.Kind
+}
+
+func (workloadComparator) Compare(old, new interface{}) []FieldChange {
+	o, _ := old.(WorkloadInfo)
+	n, _ := new.(WorkloadInfo)
+	return changedFields(
+		FieldChange{"Replicas", o.Replicas, n.Replicas},
+		FieldChange{"ReadyReplicas", o.ReadyReplicas, n.ReadyReplicas},
+		FieldChange{"UpdatedReplicas", o.UpdatedReplicas, n.UpdatedReplicas},
+		FieldChange{"Images", strings.Join(o.Images, ","), strings.Join(n.Images, ",")},
+	)
+}
+
+func (workloadComparator) Children(interface{}) map[string]map[string]interface{} {
+	return nil
+}
+
+// EventInfo is the watched state of a Kubernetes event
+type EventInfo struct {
+	Namespace string
+	Type      string // Normal or Warning
+	Reason    string
+	Object    string // Kind/name of the involved object
+	Message   string
+	Count     int32
+}
+
+// eventComparator compares EventInfo
+type eventComparator struct{}
+
+func (eventComparator) Describe(state interface{}) (string, string, interface{}) {
+	event, _ := state.(EventInfo)
+	return event.Namespace, event.Reason, event.Message
+}
+
+func (eventComparator) Compare(old, new interface{}) []FieldChange {
+	o, _ := old.(EventInfo)
+	n, _ := new.(EventInfo)
+	return changedFields(
+		FieldChange{"Count", o.Count, n.Count},
+		FieldChange{"Message", o.Message, n.Message},
+	)
+}
+
+func (eventComparator) Children(interface{}) map[string]map[string]interface{} {
+	return nil
+}
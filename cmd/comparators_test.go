@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+// changeSummary is the part of a change event compared by the tests
+type changeSummary struct {
+	ResourceType string
+	ResourceName string
+	Namespace    string
+	ChangeType   string
+	Field        string
+	OldValue     interface{}
+	NewValue     interface{}
+}
+
+// summarize drops the timestamps of change events
+func summarize(changes []ChangeEvent) []changeSummary {
+	var summaries []changeSummary
+	for _, change := range changes {
+		summaries = append(summaries, changeSummary{
+			change.ResourceType, change.ResourceName, change.Namespace,
+			change.ChangeType, change.Field, change.OldValue, change.NewValue,
+		})
+	}
+	return summaries
+}
+
+func TestNodeComparator(t *testing.T) {
+	node := NodeData{
+		Name:     "node-1",
+		Status:   NodeStatusReady,
+		Version:  "v1.27.3",
+		PodCount: "0",
+		Labels:   map[string]string{"zone": "a"},
+	}
+	modified := node
+	modified.Status = "NotReady"
+	modified.Version = "v1.28.0"
+	modified.Unschedulable = true
+	modified.Labels = map[string]string{"zone": "b", "pool": "spot"}
+
+	tests := []struct {
+		name     string
+		old, new interface{}
+		want     []changeSummary
+	}{
+		{
+			name: "added",
+			new:  node,
+			want: []changeSummary{{"Node", "node-1", "", "Added", "", nil, node}},
+		},
+		{
+			name: "removed",
+			old:  node,
+			want: []changeSummary{{"Node", "node-1", "", "Removed", "", node, nil}},
+		},
+		{
+			name: "unchanged",
+			old:  node,
+			new:  node,
+		},
+		{
+			name: "modified",
+			old:  node,
+			new:  modified,
+			want: []changeSummary{
+				{"Node", "node-1", "", "Modified", "Status", NodeStatusReady, "NotReady"},
+				{"Node", "node-1", "", "Modified", "Version", "v1.27.3", "v1.28.0"},
+				{"Node", "node-1", "", "Modified", "Unschedulable", false, true},
+				{"Node", "node-1", "", "Modified", "Label[pool]", nil, "spot"},
+				{"Node", "node-1", "", "Modified", "Label[zone]", "a", "b"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := summarize(NewComparatorRegistry().Diff("Node", "node-1", test.old, test.new, nil))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPodComparator(t *testing.T) {
+	pod := PodInfo{Name: "web-1", Namespace: "shop", Status: "Running"}
+	restarted := pod
+	restarted.Status = "CrashLoopBackOff"
+	restarted.RestartCount = 3
+	withPods := func(pods ...PodInfo) NodeData {
+		data := NodeData{Name: "node-1", Pods: make(map[string]PodInfo)}
+		for _, pod := range pods {
+			data.Pods[pod.Name] = pod
+		}
+		return data
+	}
+
+	tests := []struct {
+		name     string
+		old, new NodeData
+		want     []changeSummary
+	}{
+		{
+			name: "added",
+			old:  withPods(),
+			new:  withPods(pod),
+			want: []changeSummary{{"Pod", "node-1/web-1", "shop", "Added", "Status", nil, "Running"}},
+		},
+		{
+			name: "removed",
+			old:  withPods(pod),
+			new:  withPods(),
+			want: []changeSummary{{"Pod", "node-1/web-1", "shop", "Removed", "Status", "Running", nil}},
+		},
+		{
+			name: "modified",
+			old:  withPods(pod),
+			new:  withPods(restarted),
+			want: []changeSummary{
+				{"Pod", "node-1/web-1", "shop", "Modified", "Status", "Running", "CrashLoopBackOff"},
+				{"Pod", "node-1/web-1", "shop", "Modified", "RestartCount", 0, 3},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var removed []string
+			got := summarize(NewComparatorRegistry().Diff("Node", "node-1", test.old, test.new, func(resourceType, name string, state interface{}) {
+				removed = append(removed, resourceType+" "+name)
+			}))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+			if test.name == "removed" && !reflect.DeepEqual(removed, []string{"Pod node-1/web-1"}) {
+				t.Errorf("removed callback got %v", removed)
+			}
+		})
+	}
+}
+
+func TestContainerComparator(t *testing.T) {
+	withContainer := func(containers map[string]ContainerInfo) NodeData {
+		return NodeData{Name: "node-1", Pods: map[string]PodInfo{
+			"web-1": {Name: "web-1", Namespace: "shop", Status: "Running", ContainerInfo: containers},
+		}}
+	}
+	running := ContainerInfo{Status: "Running"}
+	restarted := ContainerInfo{Status: "Running", RestartCount: 1}
+
+	tests := []struct {
+		name     string
+		old, new NodeData
+		want     []changeSummary
+	}{
+		{
+			name: "added",
+			old:  withContainer(nil),
+			new:  withContainer(map[string]ContainerInfo{"app": running}),
+			want: []changeSummary{{"Container", "node-1/web-1/app", "shop", "Added", "Status", nil, "Running"}},
+		},
+		{
+			name: "removed",
+			old:  withContainer(map[string]ContainerInfo{"app": running}),
+			new:  withContainer(nil),
+			want: []changeSummary{{"Container", "node-1/web-1/app", "shop", "Removed", "Status", "Running", nil}},
+		},
+		{
+			name: "modified",
+			old:  withContainer(map[string]ContainerInfo{"app": running}),
+			new:  withContainer(map[string]ContainerInfo{"app": restarted}),
+			want: []changeSummary{{"Container", "node-1/web-1/app", "shop", "Modified", "RestartCount", 0, 1}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := summarize(NewComparatorRegistry().Diff("Node", "node-1", test.old, test.new, nil))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWorkloadComparator(t *testing.T) {
+	workload := WorkloadInfo{
+		Kind:            WorkloadKindDeployment,
+		Namespace:       "shop",
+		Replicas:        3,
+		ReadyReplicas:   3,
+		UpdatedReplicas: 3,
+		Images:          []string{"web:1.0"},
+	}
+	rolledOut := workload
+	rolledOut.ReadyReplicas = 2
+	rolledOut.UpdatedReplicas = 1
+	rolledOut.Images = []string{"web:1.1"}
+	name := workloadKey("shop", WorkloadKindDeployment, "web")
+
+	tests := []struct {
+		name     string
+		old, new interface{}
+		want     []changeSummary
+	}{
+		{
+			name: "added",
+			new:  workload,
+			want: []changeSummary{{"Workload", name, "shop", "Added", "Kind", nil, WorkloadKindDeployment}},
+		},
+		{
+			name: "removed",
+			old:  workload,
+			want: []changeSummary{{"Workload", name, "shop", "Removed", "Kind", WorkloadKindDeployment, nil}},
+		},
+		{
+			name: "modified",
+			old:  workload,
+			new:  rolledOut,
+			want: []changeSummary{
+				{"Workload", name, "shop", "Modified", "ReadyReplicas", int32(3), int32(2)},
+				{"Workload", name, "shop", "Modified", "UpdatedReplicas", int32(3), int32(1)},
+				{"Workload", name, "shop", "Modified", "Images", "web:1.0", "web:1.1"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := summarize(NewComparatorRegistry().Diff("Workload", name, test.old, test.new, nil))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEventComparator(t *testing.T) {
+	event := EventInfo{
+		Namespace: "shop",
+		Type:      "Warning",
+		Reason:    "BackOff",
+		Object:    "Pod/web-1",
+		Message:   "Back-off restarting failed container",
+		Count:     1,
+	}
+	repeated := event
+	repeated.Count = 4
+	name := eventKey("shop", "web-1.17a8c")
+
+	tests := []struct {
+		name     string
+		old, new interface{}
+		want     []changeSummary
+	}{
+		{
+			name: "added",
+			new:  event,
+			want: []changeSummary{{"Event", name, "shop", "Added", "BackOff", nil, event.Message}},
+		},
+		{
+			name: "removed",
+			old:  event,
+			want: []changeSummary{{"Event", name, "shop", "Removed", "BackOff", event.Message, nil}},
+		},
+		{
+			name: "modified",
+			old:  event,
+			new:  repeated,
+			want: []changeSummary{{"Event", name, "shop", "Modified", "Count", int32(1), int32(4)}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := summarize(NewComparatorRegistry().Diff("Event", name, test.old, test.new, nil))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCompareResources(t *testing.T) {
+	cache := NewStateCache()
+	web := WorkloadInfo{Kind: WorkloadKindDeployment, Namespace: "shop", Replicas: 1}
+	cache.CompareResources("Workload", map[string]interface{}{"shop/Deployment/web": web}, true)
+	cache.CompareResources("Event", map[string]interface{}{"shop/e1": EventInfo{Namespace: "shop", Reason: "BackOff"}}, false)
+
+	got := summarize(cache.CompareResources("Workload", map[string]interface{}{}, true))
+	want := []changeSummary{{"Workload", "shop/Deployment/web", "shop", "Removed", "Kind", WorkloadKindDeployment, nil}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("removed workload: got %+v, want %+v", got, want)
+	}
+
+	// Expired events are forgotten without a change, and are new if they return
+	if changes := cache.CompareResources("Event", map[string]interface{}{}, false); len(changes) != 0 {
+		t.Errorf("expired event: got %+v, want no changes", changes)
+	}
+	changes := cache.CompareResources("Event", map[string]interface{}{"shop/e1": EventInfo{Namespace: "shop", Reason: "BackOff"}}, false)
+	if len(changes) != 1 || changes[0].ChangeType != "Added" {
+		t.Errorf("returning event: got %+v, want one addition", changes)
+	}
+
+	// Nodes cached by bare name are not touched
+	cache.Compare("node-1", ResourceState{Data: NodeData{Name: "node-1"}})
+	cache.CompareResources("Workload", map[string]interface{}{}, true)
+	if _, ok := cache.Get("node-1"); !ok {
+		t.Error("node was dropped from the cache")
+	}
+}
+
+func TestUnregisteredResourceType(t *testing.T) {
+	if changes := NewComparatorRegistry().Diff("Ingress", "web", nil, "state", nil); len(changes) != 0 {
+		t.Errorf("got %v, want no changes", changes)
+	}
+}
@@ -50,6 +50,18 @@ const (
 	KeyRefresh      = 'r'
 	KeyClearHistory = 'c'
 	KeyHelp         = '?'
+	KeyEdit         = 'e'
+	KeyApplyEdit    = 'y'
+	KeyPodsLogs     = 'a'
+	KeySelectorLogs = 'l'
+	KeySortColumn   = 's'
+
+	KeyChangeLogFilter     = 'f'
+	KeyChangeLogFullScreen = 'h'
+	KeyChangeLogLogs       = 'o'
+
+	KeyAlertAcknowledge = 'a'
+	KeyAlertSilence     = 's'
 )
 
 // Dialog text
@@ -62,14 +74,28 @@ const (
 [yellow]r[white] - Refresh data
 [yellow]c[white] - Clear changelog
 [yellow]/[white] - Filter pods
+[yellow]l[white] - Tail logs of all pods matching a label selector or ~name-regex
+[yellow]s[white] - Sort nodes by the selected column, again to reverse (namespace columns sort by red pods)
 [yellow]Enter[white] - Show node details (on node columns) or pod details (on pod columns)
 [yellow]Esc[white] - Close details view or help
 [yellow]↑/↓/←/→[white] - Navigate tables
 [yellow]PgUp/PgDn[white] - Page up/down in details view
 [yellow]Home/End[white] - Jump to top/bottom in details view
+[yellow]e[white] - Edit the node or selected pod in $EDITOR (in details views)
+[yellow]a[white] - Tail logs of all listed pods together (in pod details view)
+[yellow]h[white] - Toggle the full-screen changelog
+[yellow]f[white] - Filter the changelog, e.g. type:Pod change:Modified field:Status ns:payments text (Tab to focus it first)
+[yellow]Enter/o[white] - Open the resource or the pod logs of the selected changelog entry
+[yellow]a/s[white] - Acknowledge or silence the selected alert for 1h (in the alerts pane, with --rules)
 
 [yellow]Node Details:[white] Press Enter on node columns (columns 1-5)
-[yellow]Pod Details:[white] Press Enter on pod columns (namespace columns)`
+[yellow]Pod Details:[white] Press Enter on pod columns (namespace columns)
+[yellow]Log View:[white] Press Enter on a pod, c to pick a container, p for previous instance logs,
+/ to search, n/N for next/previous match, g to grep,
+s/z to save (gzip), R to record the live stream,
+t/u to show timestamps in local time/UTC, w to cycle since 5m/1h/24h,
++/- to change the tail length, o to load older lines,
+j to show JSON/logfmt lines raw or pretty-printed, L to set the minimum level`
 )
 
 // Time intervals
@@ -0,0 +1,492 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// editHeader is prepended to the file opened in the editor
+const editHeader = `# Edit the object below and save to review the changes before they are applied.
+# Lines beginning with '#' at the top of the file are ignored.
+# Exit without saving to cancel the edit.
+`
+
+// EditView represents a full-screen view for reviewing and applying resource edits
+type EditView struct {
+	textView   *tview.TextView
+	flex       *tview.Flex
+	app        *tview.Application
+	client     *KubeClientWrapper
+	kind       string
+	namespace  string
+	name       string
+	original   string
+	edited     string
+	applyError string
+	pending    bool // True when there is an edited version waiting to be applied
+	fetching   bool // True while the resource is being fetched for editing
+	applying   bool // True while an edit is being applied in the background
+	generation int  // Incremented per edited resource, so late apply results are dropped
+}
+
+// NewEditView creates a new EditView instance
+func NewEditView() *EditView {
+	editView := &EditView{
+		textView: tview.NewTextView().
+			SetDynamicColors(true).
+			SetScrollable(true).
+			SetWrap(false).
+			SetTextColor(tcell.ColorWhite),
+	}
+
+	editView.textView.SetBorder(true).
+		SetBorderColor(tcell.ColorGray)
+
+	// Create a flex container for the edit view
+	editView.flex = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(editView.textView, 0, 1, true)
+
+	return editView
+}
+
+// SetApplication sets the tview application reference
+func (ev *EditView) SetApplication(app *tview.Application) {
+	ev.app = app
+}
+
+// GetFlex returns the flex container
+func (ev *EditView) GetFlex() *tview.Flex {
+	return ev.flex
+}
+
+// GetTextView returns the underlying text view
+func (ev *EditView) GetTextView() *tview.TextView {
+	return ev.textView
+}
+
+// HasPendingChanges returns whether an edit is waiting to be applied
+func (ev *EditView) HasPendingChanges() bool {
+	return ev.pending
+}
+
+// CanReopen returns whether the editor can be opened again for the current resource
+func (ev *EditView) CanReopen() bool {
+	return ev.client != nil && ev.original != ""
+}
+
+// Edit fetches the resource in the background and opens it in the user's
+// editor once it arrives, unless the edit was cancelled by then
+func (ev *EditView) Edit(client *KubeClientWrapper, kind, namespace, name string) {
+	ev.client = client
+	ev.kind = kind
+	ev.namespace = namespace
+	ev.name = name
+	ev.original = ""
+	ev.edited = ""
+	ev.applyError = ""
+	ev.pending = false
+	ev.applying = false
+	ev.fetching = false
+	ev.generation++
+	ev.setTitle("")
+
+	if client == nil {
+		ev.showMessage("[red]Editing resources requires a connection to a real cluster.[white]\n\nPress Esc to return.")
+		return
+	}
+
+	ev.fetching = true
+	ev.showMessage(fmt.Sprintf("Fetching %s %s...\n\nPress Esc to cancel.", kind, tview.Escape(name)))
+
+	generation := ev.generation
+	go func() {
+		content, err := fetchResourceYAML(client, kind, namespace, name)
+		ev.app.QueueUpdateDraw(func() {
+			if generation != ev.generation || !ev.fetching {
+				return // Cancelled, or another resource is being edited by now
+			}
+			ev.fetching = false
+			if err != nil {
+				ev.showMessage(fmt.Sprintf("[red]Error fetching %s %s: %s[white]\n\nPress Esc to return.", kind, tview.Escape(name), tview.Escape(err.Error())))
+				return
+			}
+			ev.original = content
+			ev.edited = content
+			ev.Reopen()
+		})
+	}()
+}
+
+// Cancel stops a fetch in progress from opening the editor, e.g. when the
+// view is closed before the resource arrived
+func (ev *EditView) Cancel() {
+	ev.fetching = false
+}
+
+// Reopen opens the editor again with the most recent edited content
+func (ev *EditView) Reopen() {
+	if !ev.CanReopen() || ev.applying {
+		return
+	}
+
+	content := editHeader
+	if ev.applyError != "" {
+		content += "#\n# The previous edit could not be applied:\n"
+		for _, line := range strings.Split(ev.applyError, "\n") {
+			content += "#   " + line + "\n"
+		}
+	}
+	content += ev.edited
+
+	result, err := runEditor(ev.app, content)
+	if err != nil {
+		ev.pending = false
+		ev.showMessage(fmt.Sprintf("[red]Error running editor: %s[white]\n\nPress e to try again, Esc to cancel.", tview.Escape(err.Error())))
+		return
+	}
+
+	ev.edited = stripEditHeader(result)
+	ev.applyError = ""
+
+	if strings.TrimSpace(ev.edited) == strings.TrimSpace(ev.original) {
+		ev.pending = false
+		ev.showMessage("Edit cancelled, no changes made.\n\nPress e to edit again, Esc to return.")
+		return
+	}
+
+	ev.pending = true
+	ev.setTitle("y to apply, e to edit again, Esc to cancel")
+	ev.textView.SetText(formatDiff(ev.original, ev.edited))
+	ev.textView.ScrollToBeginning()
+}
+
+// Apply applies the edited content to the cluster in the background, so a
+// slow API server doesn't block the UI. The result is shown on the UI
+// goroutine, where onApplied is called once the changes were applied.
+func (ev *EditView) Apply(onApplied func()) {
+	if !ev.pending || ev.applying {
+		return
+	}
+	ev.pending = false
+	ev.applying = true
+	ev.showMessage(fmt.Sprintf("Applying changes to %s %s...", ev.kind, tview.Escape(ev.name)))
+
+	generation := ev.generation
+	client, kind, namespace, name := ev.client, ev.kind, ev.namespace, ev.name
+	original, edited := ev.original, ev.edited
+	go func() {
+		err := applyResourceYAML(client, kind, namespace, name, edited)
+		var latest string
+		var fetchErr error
+		if apierrors.IsConflict(err) {
+			latest, fetchErr = fetchResourceYAML(client, kind, namespace, name)
+		}
+		ev.app.QueueUpdateDraw(func() {
+			if generation != ev.generation {
+				return // Another resource is being edited by now
+			}
+			ev.applying = false
+			if err == nil {
+				onApplied()
+				return
+			}
+
+			ev.applyError = err.Error()
+			message := fmt.Sprintf("[red]Error applying changes: %s[white]", tview.Escape(err.Error()))
+			if apierrors.IsConflict(err) && fetchErr == nil {
+				// Keep the user's edits on the latest resourceVersion, listing
+				// the changes made on the server in the header to reconcile with
+				ev.applyError += "\n\nChanged on the server since you opened it:\n" + plainDiff(original, latest)
+				ev.original = latest
+				ev.edited = withResourceVersion(edited, latest)
+				message += "\n\nThe resource was modified after it was opened. The editor will reopen with your changes; the changes made on the server are listed in the header and are overwritten unless you merge them."
+			}
+			ev.showMessage(message + "\n\nPress e to reopen the editor with your changes, Esc to cancel.")
+		})
+	}()
+}
+
+// resourceVersionLine matches the resourceVersion of the object's metadata
+var resourceVersionLine = regexp.MustCompile(`(?m)^  resourceVersion: .*$`)
+
+// withResourceVersion replaces the metadata resourceVersion of content with
+// the one of latest, so the content can be applied over the latest version
+func withResourceVersion(content, latest string) string {
+	version := resourceVersionLine.FindString(latest)
+	if version == "" || !resourceVersionLine.MatchString(content) {
+		return content
+	}
+	replaced := false
+	return resourceVersionLine.ReplaceAllStringFunc(content, func(line string) string {
+		if replaced {
+			return line
+		}
+		replaced = true
+		return version
+	})
+}
+
+// setTitle updates the border title with the resource and an optional hint
+func (ev *EditView) setTitle(hint string) {
+	title := fmt.Sprintf(" Edit %s: %s ", ev.kind, ev.name)
+	if ev.namespace != "" {
+		title = fmt.Sprintf(" Edit %s: %s/%s ", ev.kind, ev.namespace, ev.name)
+	}
+	if hint != "" {
+		title += fmt.Sprintf("(%s) ", hint)
+	}
+	ev.textView.SetTitle(title)
+}
+
+// showMessage replaces the view content with a message
+func (ev *EditView) showMessage(message string) {
+	ev.setTitle("")
+	ev.textView.SetText(message)
+	ev.textView.ScrollToBeginning()
+}
+
+// fetchResourceYAML retrieves the current state of a resource as YAML
+func fetchResourceYAML(client *KubeClientWrapper, kind, namespace, name string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), APITimeout)
+	defer cancel()
+
+	var obj interface{}
+	switch kind {
+	case "Node":
+		node, err := client.Clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		node.APIVersion, node.Kind = "v1", "Node"
+		node.ManagedFields = nil
+		obj = node
+	case "Pod":
+		pod, err := client.Clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		pod.APIVersion, pod.Kind = "v1", "Pod"
+		pod.ManagedFields = nil
+		obj = pod
+	default:
+		return "", fmt.Errorf("editing %s resources is not supported", kind)
+	}
+
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s: %v", kind, err)
+	}
+	return string(data), nil
+}
+
+// applyResourceYAML validates the edited YAML and updates the resource. The
+// resourceVersion from the edited object is sent along, so the API server
+// rejects the update with a conflict if the resource changed in the meantime.
+func applyResourceYAML(client *KubeClientWrapper, kind, namespace, name, content string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), APITimeout)
+	defer cancel()
+
+	opts := metav1.UpdateOptions{FieldManager: "kubism"}
+
+	switch kind {
+	case "Node":
+		var node corev1.Node
+		if err := yaml.UnmarshalStrict([]byte(content), &node); err != nil {
+			return fmt.Errorf("invalid YAML: %v", err)
+		}
+		if node.Name != name {
+			return fmt.Errorf("the name cannot be changed (was %q, now %q)", name, node.Name)
+		}
+		_, err := client.Clientset.CoreV1().Nodes().Update(ctx, &node, opts)
+		return err
+	case "Pod":
+		var pod corev1.Pod
+		if err := yaml.UnmarshalStrict([]byte(content), &pod); err != nil {
+			return fmt.Errorf("invalid YAML: %v", err)
+		}
+		if pod.Name != name || pod.Namespace != namespace {
+			return fmt.Errorf("the name and namespace cannot be changed (was %s/%s, now %s/%s)", namespace, name, pod.Namespace, pod.Name)
+		}
+		_, err := client.Clientset.CoreV1().Pods(namespace).Update(ctx, &pod, opts)
+		return err
+	default:
+		return fmt.Errorf("editing %s resources is not supported", kind)
+	}
+}
+
+// runEditor suspends the UI and opens the content in $KUBE_EDITOR or $EDITOR
+func runEditor(app *tview.Application, content string) (string, error) {
+	file, err := os.CreateTemp("", "kubism-edit-*.yaml")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return "", err
+	}
+	file.Close()
+
+	editor := os.Getenv("KUBE_EDITOR")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	args := strings.Fields(editor)
+
+	var runErr error
+	run := func() {
+		cmd := exec.Command(args[0], append(args[1:], file.Name())...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		runErr = cmd.Run()
+	}
+	if app == nil || !app.Suspend(run) {
+		return "", fmt.Errorf("unable to suspend the terminal UI")
+	}
+	if runErr != nil {
+		return "", fmt.Errorf("%s: %v", editor, runErr)
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// stripEditHeader removes the leading comment lines added before editing
+func stripEditHeader(content string) string {
+	lines := strings.Split(content, "\n")
+	i := 0
+	for i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "#") {
+		i++
+	}
+	return strings.Join(lines[i:], "\n")
+}
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// diffLine is a single line of a line-based diff
+type diffLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+}
+
+// computeDiff returns the line diff between two texts using their longest common subsequence
+func computeDiff(oldText, newText string) []diffLine {
+	oldLines := strings.Split(strings.TrimRight(oldText, "\n"), "\n")
+	newLines := strings.Split(strings.TrimRight(newText, "\n"), "\n")
+
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffLine
+	i, j := 0, 0
+	for i < len(oldLines) && j < len(newLines) {
+		switch {
+		case oldLines[i] == newLines[j]:
+			ops = append(ops, diffLine{' ', oldLines[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffLine{'-', oldLines[i]})
+			i++
+		default:
+			ops = append(ops, diffLine{'+', newLines[j]})
+			j++
+		}
+	}
+	for ; i < len(oldLines); i++ {
+		ops = append(ops, diffLine{'-', oldLines[i]})
+	}
+	for ; j < len(newLines); j++ {
+		ops = append(ops, diffLine{'+', newLines[j]})
+	}
+	return ops
+}
+
+// plainDiff returns only the changed lines of a diff without color tags
+func plainDiff(oldText, newText string) string {
+	var lines []string
+	for _, line := range computeDiff(oldText, newText) {
+		if line.op != ' ' {
+			lines = append(lines, string(line.op)+" "+line.text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatDiff renders a colored line diff between two texts
+func formatDiff(oldText, newText string) string {
+	ops := computeDiff(oldText, newText)
+
+	// Mark unchanged lines that are close enough to a change to be shown
+	visible := make([]bool, len(ops))
+	for idx, op := range ops {
+		if op.op == ' ' {
+			continue
+		}
+		for k := idx - diffContext; k <= idx+diffContext; k++ {
+			if k >= 0 && k < len(ops) {
+				visible[k] = true
+			}
+		}
+	}
+
+	var sb strings.Builder
+	skipped := false
+	for idx, op := range ops {
+		if !visible[idx] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			sb.WriteString("[gray]...[white]\n")
+			skipped = false
+		}
+		text := tview.Escape(op.text)
+		switch op.op {
+		case '-':
+			sb.WriteString("[red]- " + text + "[white]\n")
+		case '+':
+			sb.WriteString("[green]+ " + text + "[white]\n")
+		default:
+			sb.WriteString("[gray]  " + text + "[white]\n")
+		}
+	}
+	if skipped {
+		sb.WriteString("[gray]...[white]\n")
+	}
+	return sb.String()
+}
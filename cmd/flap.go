@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// FlapWindow is how long after the last change to a resource field a repeated
+	// change is coalesced into the same change log row
+	FlapWindow = 5 * time.Minute
+
+	// FlapThreshold is the number of coalesced changes at which a resource is
+	// marked as flapping in the main table
+	FlapThreshold = 3
+
+	// flapMarker is appended to the main table cells of flapping nodes and pods
+	flapMarker = " [fuchsia::b]↯[-::-]"
+)
+
+// flapKey identifies the resource field a change applies to
+type flapKey struct {
+	resourceType string
+	resourceName string
+	field        string
+}
+
+// newFlapKey returns the key of a change, ok is false for changes that are
+// never coalesced such as additions and removals
+func newFlapKey(change ChangeEvent) (key flapKey, ok bool) {
+	if change.ChangeType != "Modified" {
+		return flapKey{}, false
+	}
+	return flapKey{change.ResourceType, change.ResourceName, change.Field}, true
+}
+
+// flapSet holds the nodes, and the namespaces on each node, with flapping resources
+type flapSet struct {
+	nodes      map[string]bool
+	namespaces map[string]map[string]bool // Node name -> namespaces with flapping pods
+}
+
+// newFlapSet collects the nodes and namespaces of flapping changes
+func newFlapSet(changes []ChangeEvent) flapSet {
+	set := flapSet{
+		nodes:      make(map[string]bool),
+		namespaces: make(map[string]map[string]bool),
+	}
+	for _, change := range changes {
+		nodeName, _, _ := strings.Cut(change.ResourceName, "/")
+		switch change.ResourceType {
+		case "Node":
+			set.nodes[nodeName] = true
+			continue
+		case "Pod", "Container":
+		default:
+			continue // Workloads and events are not shown in the main table
+		}
+		if set.namespaces[nodeName] == nil {
+			set.namespaces[nodeName] = make(map[string]bool)
+		}
+		set.namespaces[nodeName][change.Namespace] = true
+	}
+	return set
+}
+
+// formatFlapDuration formats the time range of coalesced changes, e.g. 45s, 3m or 1h5m
+func formatFlapDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -104,9 +104,11 @@ func (p *BaseK8sDataProvider) filterAndTransformData(
 			Status:    nodeStatus,
 			Version:   raw.Node.Status.NodeInfo.KubeletVersion,
 			Age:       FormatDuration(time.Since(raw.Node.CreationTimestamp.Time)),
+			Created:   raw.Node.CreationTimestamp.Time,
 			Pods:      make(map[string]PodInfo),
 			TotalPods: len(raw.Pods), // Store total unfiltered count
 		}
+		data.setNodeProperties(raw.Node)
 
 		// Initialize pod indicators structure
 		podsByNode[nodeName] = make(map[string][]string)
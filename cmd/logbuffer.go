@@ -0,0 +1,344 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// DefaultLogMaxLines is the default number of lines kept in the log view
+const DefaultLogMaxLines = 10000
+
+// logLine is a single buffered log line
+type logLine struct {
+	target     logTarget      // Stream the line came from, empty for notes not tied to a stream
+	source     string         // Container or pod/container the line came from, empty for single streams
+	color      string         // Color of the source prefix
+	text       string         // Raw line text without the trailing newline
+	timestamp  time.Time      // Server timestamp, zero for notes
+	note       bool           // True for messages generated by the log view itself, e.g. errors
+	structured *structuredLog // Parsed JSON or logfmt line, nil for plain text
+}
+
+// logBuffer holds the lines shown in the log view in a fixed-size ring and
+// renders them with the active search highlight and grep filter applied
+type logBuffer struct {
+	mu         sync.Mutex
+	lines      []logLine // Ring storage, allocated up to maxLines
+	start      int       // Index of the oldest line in the ring
+	count      int       // Number of lines in the ring
+	maxLines   int
+	dropped    int            // Lines dropped from the ring since the last render
+	generation int            // Incremented on reset so stale streams can't append
+	search     *regexp.Regexp // Highlighted in rendered lines
+	filter     *regexp.Regexp // Only matching lines are rendered
+	matches    int            // Number of search matches rendered so far
+	timestamps bool           // Show server timestamps in rendered lines
+	location   *time.Location // Time zone of rendered timestamps
+	raw        bool           // Show structured lines as logged instead of pretty-printed
+	minLevel   logLevel       // Hide structured lines below this level
+}
+
+// logTargetStats describes the buffered lines of one stream
+type logTargetStats struct {
+	oldest time.Time // Timestamp of the oldest buffered line
+	count  int       // Number of buffered lines
+}
+
+// logTimestampFormat is used for timestamps shown in front of log lines
+const logTimestampFormat = "2006-01-02 15:04:05.000"
+
+// compileLogQuery turns user input into a regular expression. Input wrapped in
+// slashes is used as a regular expression, anything else matches as a
+// case-insensitive substring.
+func compileLogQuery(query string) (*regexp.Regexp, error) {
+	if query == "" {
+		return nil, nil
+	}
+	if len(query) >= 2 && strings.HasPrefix(query, "/") && strings.HasSuffix(query, "/") {
+		return regexp.Compile(query[1 : len(query)-1])
+	}
+	return regexp.Compile("(?i)" + regexp.QuoteMeta(query))
+}
+
+// SetMaxLines sets the capacity of the ring, discarding buffered lines
+func (b *logBuffer) SetMaxLines(maxLines int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if maxLines <= 0 {
+		maxLines = DefaultLogMaxLines
+	}
+	b.maxLines = maxLines
+	b.lines, b.start, b.count, b.dropped = nil, 0, 0, 0
+}
+
+// Reset removes all lines and returns the new generation
+func (b *logBuffer) Reset() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lines, b.start, b.count, b.dropped = b.lines[:0], 0, 0, 0
+	b.matches = 0
+	b.generation++
+	return b.generation
+}
+
+// Len returns the number of buffered lines
+func (b *logBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.count
+}
+
+// push stores a line in the ring, overwriting the oldest line when full.
+// Must be called with the lock held.
+func (b *logBuffer) push(line logLine) {
+	if b.maxLines <= 0 {
+		b.maxLines = DefaultLogMaxLines
+	}
+	if b.count < b.maxLines {
+		if len(b.lines) < b.maxLines {
+			b.lines = append(b.lines, line)
+		} else {
+			b.lines[(b.start+b.count)%b.maxLines] = line
+		}
+		b.count++
+		return
+	}
+	b.lines[b.start] = line
+	b.start = (b.start + 1) % b.maxLines
+	b.dropped++
+}
+
+// each calls fn for every buffered line from oldest to newest.
+// Must be called with the lock held.
+func (b *logBuffer) each(fn func(line logLine)) {
+	for i := 0; i < b.count; i++ {
+		fn(b.lines[(b.start+i)%len(b.lines)])
+	}
+}
+
+// Lines returns a copy of the buffered lines from oldest to newest
+func (b *logBuffer) Lines() []logLine {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lines := make([]logLine, 0, b.count)
+	b.each(func(line logLine) {
+		lines = append(lines, line)
+	})
+	return lines
+}
+
+// Generation returns the current generation
+func (b *logBuffer) Generation() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.generation
+}
+
+// SetSearch sets the expression highlighted in rendered lines
+func (b *logBuffer) SetSearch(search *regexp.Regexp) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.search = search
+}
+
+// SetFilter sets the expression lines must match to be rendered
+func (b *logBuffer) SetFilter(filter *regexp.Regexp) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.filter = filter
+}
+
+// SetTimestamps sets whether rendered lines start with their timestamp and in which time zone
+func (b *logBuffer) SetTimestamps(show bool, location *time.Location) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.timestamps = show
+	b.location = location
+}
+
+// SetStructured sets whether structured lines are pretty-printed and the minimum level shown
+func (b *logBuffer) SetStructured(raw bool, minLevel logLevel) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.raw = raw
+	b.minLevel = minLevel
+}
+
+// TargetStats returns the oldest timestamp and number of buffered lines of every stream
+func (b *logBuffer) TargetStats() map[logTarget]logTargetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := make(map[logTarget]logTargetStats)
+	b.each(func(line logLine) {
+		if line.note || line.timestamp.IsZero() {
+			return
+		}
+		stat := stats[line.target]
+		if stat.oldest.IsZero() || line.timestamp.Before(stat.oldest) {
+			stat.oldest = line.timestamp
+		}
+		stat.count++
+		stats[line.target] = stat
+	})
+	return stats
+}
+
+// Prepend inserts older lines before the buffered ones, keeping only as many of
+// the newest as fit in the ring. It reports the number of lines added and
+// whether the ring is full. Lines from an older generation are dropped.
+func (b *logBuffer) Prepend(generation int, older []logLine) (int, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.maxLines <= 0 {
+		b.maxLines = DefaultLogMaxLines
+	}
+	if generation != b.generation {
+		return 0, false
+	}
+	room := b.maxLines - b.count
+	if len(older) > room {
+		older = older[len(older)-room:]
+	}
+	if len(older) == 0 {
+		return 0, room == 0
+	}
+
+	lines := make([]logLine, 0, len(older)+b.count)
+	lines = append(lines, older...)
+	b.each(func(line logLine) {
+		lines = append(lines, line)
+	})
+	b.lines, b.start, b.count = lines, 0, len(lines)
+	return len(older), b.count == b.maxLines
+}
+
+// Matches returns the number of search matches currently rendered
+func (b *logBuffer) Matches() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.matches
+}
+
+// Append adds a line and writes it to w if it passes the filter. Lines from an
+// older generation are dropped.
+func (b *logBuffer) Append(generation int, line logLine, w io.Writer) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation != b.generation {
+		return false
+	}
+	b.push(line)
+
+	// The text view keeps everything written to it, so once a tenth of the
+	// ring has been replaced, redraw it from the ring to bound its size too
+	if textView, ok := w.(*tview.TextView); ok && b.dropped > b.maxLines/10 {
+		b.render(textView)
+		return true
+	}
+
+	if rendered, ok := b.renderLine(line); ok {
+		w.Write([]byte(rendered))
+		return true
+	}
+	return false
+}
+
+// Render redraws all buffered lines into the text view
+func (b *logBuffer) Render(textView *tview.TextView) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.render(textView)
+}
+
+// render redraws all buffered lines. Must be called with the lock held.
+func (b *logBuffer) render(textView *tview.TextView) {
+	b.matches = 0
+	b.dropped = 0
+	var sb strings.Builder
+	b.each(func(line logLine) {
+		if rendered, ok := b.renderLine(line); ok {
+			sb.WriteString(rendered)
+		}
+	})
+	textView.SetText(sb.String())
+}
+
+// prefix returns the color-tagged source prefix of a line
+func (line logLine) prefix() string {
+	if line.source == "" {
+		return ""
+	}
+	return fmt.Sprintf("[%s]%s[-] ", line.color, tview.Escape(line.source))
+}
+
+// timestampPrefix returns the color-tagged timestamp of a line if timestamps
+// are shown. Must be called with the lock held.
+func (b *logBuffer) timestampPrefix(line logLine) string {
+	if !b.timestamps || line.timestamp.IsZero() {
+		return ""
+	}
+	location := b.location
+	if location == nil {
+		location = time.Local
+	}
+	return "[gray]" + line.timestamp.In(location).Format(logTimestampFormat) + "[-] "
+}
+
+// renderLine formats a line for display. Must be called with the lock held.
+func (b *logBuffer) renderLine(line logLine) (string, bool) {
+	if line.note {
+		return line.prefix() + "[red]" + tview.Escape(line.text) + "[-]\n", true
+	}
+
+	// Lines without a level are kept, they are often continuations like stack traces
+	text, color := line.text, ""
+	if line.structured != nil {
+		if b.minLevel != logLevelNone && line.structured.level != logLevelNone && line.structured.level < b.minLevel {
+			return "", false
+		}
+		color = line.structured.level.color()
+		if !b.raw {
+			text = line.structured.format(b.location)
+		}
+	}
+	if b.filter != nil && !b.filter.MatchString(text) {
+		return "", false
+	}
+
+	var sb strings.Builder
+	sb.WriteString(b.timestampPrefix(line))
+	sb.WriteString(line.prefix())
+	if color != "" {
+		sb.WriteString("[" + color + "]")
+	}
+	if b.search == nil {
+		sb.WriteString(tview.Escape(text))
+	} else {
+		// Wrap every match in a region so it can be highlighted and scrolled to
+		last := 0
+		for _, loc := range b.search.FindAllStringIndex(text, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			sb.WriteString(tview.Escape(text[last:loc[0]]))
+			fmt.Fprintf(&sb, `["m%d"][black:yellow]%s[-:-][""]`, b.matches, tview.Escape(text[loc[0]:loc[1]]))
+			if color != "" {
+				sb.WriteString("[" + color + "]") // The highlight resets the level color
+			}
+			b.matches++
+			last = loc[1]
+		}
+		sb.WriteString(tview.Escape(text[last:]))
+	}
+	if color != "" {
+		sb.WriteString("[-]")
+	}
+	sb.WriteString("\n")
+	return sb.String(), true
+}
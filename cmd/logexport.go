@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// unsafeFileChars matches characters that are replaced in exported file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// logRecorder tees newly streamed log lines to a file
+type logRecorder struct {
+	mu   sync.Mutex
+	file *os.File
+	path string
+}
+
+// formatExportLine formats a log line for writing to a file
+func formatExportLine(line logLine) string {
+	var sb strings.Builder
+	if !line.timestamp.IsZero() {
+		sb.WriteString(line.timestamp.Format(time.RFC3339Nano))
+		sb.WriteString(" ")
+	}
+	if line.source != "" {
+		sb.WriteString("[" + line.source + "] ")
+	}
+	sb.WriteString(line.text)
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// exportFileName builds a file name from the cluster, namespace, pod, container and time
+func (l *LogView) exportFileName(ext string) string {
+	var parts []string
+	if l.mainApp != nil {
+		parts = append(parts, l.mainApp.GetProvider().GetClusterName())
+	}
+	switch {
+	case l.selector != nil:
+		parts = append(parts, l.selector.Description)
+	case l.pod != nil:
+		container := l.container
+		if container == "" {
+			container = "all"
+		}
+		parts = append(parts, l.pod.Namespace, l.pod.Name, container)
+		if l.previous {
+			parts = append(parts, "previous")
+		}
+	}
+	parts = append(parts, time.Now().Format("20060102-150405"))
+
+	for i, part := range parts {
+		parts[i] = strings.Trim(unsafeFileChars.ReplaceAllString(part, "-"), "-")
+	}
+	return filepath.Join(l.exportDir, strings.Join(parts, "_")+ext)
+}
+
+// SaveBuffer writes the buffered log lines to a new file, optionally
+// gzip-compressed. The file is removed if it can't be written completely.
+func (l *LogView) SaveBuffer(compress bool) (string, error) {
+	ext := ".log"
+	if compress {
+		ext = ".log.gz"
+	}
+	path := l.exportFileName(ext)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	err = writeExportLines(file, l.buffer.Lines(), compress)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// writeExportLines writes the log lines, except notes, optionally gzip-compressed
+func writeExportLines(w io.Writer, lines []logLine, compress bool) error {
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(w)
+		w = gz
+	}
+	for _, line := range lines {
+		if line.note {
+			continue
+		}
+		if _, err := io.WriteString(w, formatExportLine(line)); err != nil {
+			return err
+		}
+	}
+	if gz != nil {
+		return gz.Close()
+	}
+	return nil
+}
+
+// ToggleRecording starts or stops teeing the live stream to a file
+func (l *LogView) ToggleRecording() (string, error) {
+	l.recorder.mu.Lock()
+	defer l.recorder.mu.Unlock()
+
+	if l.recorder.file != nil {
+		path := l.recorder.path
+		err := l.recorder.file.Close()
+		l.recorder.file = nil
+		return path, err
+	}
+
+	path := l.exportFileName(".log")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	l.recorder.file = file
+	l.recorder.path = path
+	return path, nil
+}
+
+// IsRecording returns whether the live stream is being written to a file
+func (l *LogView) IsRecording() bool {
+	l.recorder.mu.Lock()
+	defer l.recorder.mu.Unlock()
+	return l.recorder.file != nil
+}
+
+// stopRecording closes the recording file if one is open
+func (l *LogView) stopRecording() {
+	l.recorder.mu.Lock()
+	defer l.recorder.mu.Unlock()
+	if l.recorder.file != nil {
+		l.recorder.file.Close()
+		l.recorder.file = nil
+	}
+}
+
+// record writes a line to the recording file if recording is active
+func (l *LogView) record(line logLine) {
+	if line.note {
+		return
+	}
+	l.recorder.mu.Lock()
+	if l.recorder.file == nil {
+		l.recorder.mu.Unlock()
+		return
+	}
+	_, err := l.recorder.file.WriteString(formatExportLine(line))
+	if err != nil {
+		// Stop recording rather than failing on every line
+		l.recorder.file.Close()
+		l.recorder.file = nil
+	}
+	l.recorder.mu.Unlock()
+
+	// Queued without the lock, the UI goroutine takes it in IsRecording
+	if err != nil && l.app != nil {
+		l.app.QueueUpdateDraw(func() {
+			l.setStatus(fmt.Sprintf("[red]Recording stopped: %v", err))
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// logLevel is the severity of a structured log line
+type logLevel int
+
+const (
+	logLevelNone logLevel = iota // No or unknown level
+	logLevelDebug
+	logLevelInfo
+	logLevelWarn
+	logLevelError
+)
+
+// logLevelNames are the names shown for each level
+var logLevelNames = map[logLevel]string{
+	logLevelNone:  "all",
+	logLevelDebug: "debug",
+	logLevelInfo:  "info",
+	logLevelWarn:  "warn",
+	logLevelError: "error",
+}
+
+// Keys commonly used by logging libraries for the time, level and message
+var (
+	logTimeKeys    = []string{"time", "ts", "timestamp", "@timestamp"}
+	logLevelKeys   = []string{"level", "lvl", "severity", "loglevel", "@level"}
+	logMessageKeys = []string{"msg", "message", "@message"}
+)
+
+// String returns the name of the level
+func (lv logLevel) String() string {
+	return logLevelNames[lv]
+}
+
+// color returns the color lines of the level are shown in, empty for the default color
+func (lv logLevel) color() string {
+	switch lv {
+	case logLevelError:
+		return "red"
+	case logLevelWarn:
+		return "yellow"
+	case logLevelDebug:
+		return "gray"
+	}
+	return ""
+}
+
+// next returns the following minimum level, wrapping around to all levels
+func (lv logLevel) next() logLevel {
+	if lv >= logLevelError {
+		return logLevelNone
+	}
+	return lv + 1
+}
+
+// parseLogLevel maps the level names of common logging libraries to a level
+func parseLogLevel(value string) logLevel {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "trace", "debug", "dbug", "dbg", "d", "10", "20":
+		return logLevelDebug
+	case "info", "information", "notice", "inf", "i", "30":
+		return logLevelInfo
+	case "warn", "warning", "wrn", "w", "40":
+		return logLevelWarn
+	case "error", "err", "eror", "e", "fatal", "panic", "critical", "crit", "alert", "emergency", "dpanic", "50", "60":
+		return logLevelError
+	}
+	return logLevelNone
+}
+
+// logField is a key/value pair of a structured log line
+type logField struct {
+	key   string
+	value string
+}
+
+// structuredLog is a JSON or logfmt log line split into its parts
+type structuredLog struct {
+	time      string   // Time as logged, empty if missing
+	level     logLevel // Parsed level
+	levelText string   // Level as logged
+	message   string
+	fields    []logField // Remaining fields in display order
+}
+
+// parseStructuredLog detects JSON and logfmt lines, returning nil for plain text
+func parseStructuredLog(text string) *structuredLog {
+	trimmed := strings.TrimSpace(text)
+	if strings.HasPrefix(trimmed, "{") && strings.HasSuffix(trimmed, "}") {
+		return parseJSONLog(trimmed)
+	}
+	if strings.Contains(trimmed, "=") {
+		return parseLogfmt(trimmed)
+	}
+	return nil
+}
+
+// parseJSONLog parses a JSON object log line
+func parseJSONLog(text string) *structuredLog {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil {
+		return nil
+	}
+
+	values := make(map[string]string, len(object))
+	keys := make([]string, 0, len(object))
+	for key, value := range object {
+		keys = append(keys, key)
+		switch v := value.(type) {
+		case string:
+			values[key] = v
+		case nil:
+			values[key] = "null"
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil
+			}
+			values[key] = string(data)
+		}
+	}
+	sort.Strings(keys)
+	return newStructuredLog(keys, values)
+}
+
+// parseLogfmt parses a logfmt line. Lines are only accepted when every token
+// is a key=value pair and a level or message key is present, so prose that
+// happens to contain an equals sign is left alone.
+func parseLogfmt(text string) *structuredLog {
+	var keys []string
+	values := make(map[string]string)
+	for i := 0; i < len(text); {
+		if text[i] == ' ' {
+			i++
+			continue
+		}
+
+		// Key up to the equals sign
+		start := i
+		for i < len(text) && text[i] != '=' && text[i] != ' ' {
+			i++
+		}
+		if i >= len(text) || text[i] != '=' || i == start {
+			return nil
+		}
+		key := text[start:i]
+		i++
+
+		// Quoted or bare value
+		var value string
+		if i < len(text) && text[i] == '"' {
+			end := i + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(text) {
+				return nil
+			}
+			unquoted, err := strconv.Unquote(text[i : end+1])
+			if err != nil {
+				return nil
+			}
+			value = unquoted
+			i = end + 1
+		} else {
+			start = i
+			for i < len(text) && text[i] != ' ' {
+				i++
+			}
+			value = text[start:i]
+		}
+
+		if _, exists := values[key]; !exists {
+			keys = append(keys, key)
+		}
+		values[key] = value
+	}
+
+	if len(keys) < 2 {
+		return nil
+	}
+	if firstKey(values, logLevelKeys) == "" && firstKey(values, logMessageKeys) == "" {
+		return nil
+	}
+	return newStructuredLog(keys, values)
+}
+
+// newStructuredLog picks the time, level and message out of the parsed fields
+func newStructuredLog(keys []string, values map[string]string) *structuredLog {
+	entry := &structuredLog{}
+	used := make(map[string]bool)
+	if key := firstKey(values, logTimeKeys); key != "" {
+		entry.time = values[key]
+		used[key] = true
+	}
+	if key := firstKey(values, logLevelKeys); key != "" {
+		entry.levelText = values[key]
+		entry.level = parseLogLevel(entry.levelText)
+		used[key] = true
+	}
+	if key := firstKey(values, logMessageKeys); key != "" {
+		entry.message = values[key]
+		used[key] = true
+	}
+	if entry.time == "" && entry.levelText == "" && entry.message == "" {
+		return nil
+	}
+	for _, key := range keys {
+		if !used[key] {
+			entry.fields = append(entry.fields, logField{key: key, value: values[key]})
+		}
+	}
+	return entry
+}
+
+// firstKey returns the first of the candidate keys present in values
+func firstKey(values map[string]string, candidates []string) string {
+	for _, key := range candidates {
+		if _, ok := values[key]; ok {
+			return key
+		}
+	}
+	return ""
+}
+
+// format renders the line as "time level message key=value…", showing
+// times the logger wrote in a known format in the given time zone
+func (s *structuredLog) format(location *time.Location) string {
+	var parts []string
+	if s.time != "" {
+		parts = append(parts, formatStructuredTime(s.time, location))
+	}
+	if s.levelText != "" {
+		level := strings.ToUpper(s.levelText)
+		if s.level != logLevelNone {
+			level = strings.ToUpper(s.level.String())
+		}
+		parts = append(parts, fmt.Sprintf("%-5s", level))
+	}
+	if s.message != "" {
+		parts = append(parts, s.message)
+	}
+	for _, field := range s.fields {
+		parts = append(parts, field.key+"="+quoteLogValue(field.value))
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatStructuredTime reformats RFC3339 and Unix epoch times, leaving other formats as logged
+func formatStructuredTime(value string, location *time.Location) string {
+	if location == nil {
+		location = time.Local
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t.In(location).Format(logTimestampFormat)
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 1e9 {
+		// Epochs in milliseconds are common too
+		if seconds > 1e12 {
+			seconds /= 1000
+		}
+		whole := int64(seconds)
+		t := time.Unix(whole, int64((seconds-float64(whole))*1e9))
+		return t.In(location).Format(logTimestampFormat)
+	}
+	return value
+}
+
+// quoteLogValue quotes values that would be ambiguous in key=value output
+func quoteLogValue(value string) string {
+	if value == "" || strings.IndexFunc(value, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '=' || r == '"'
+	}) >= 0 {
+		return strconv.Quote(value)
+	}
+	return value
+}
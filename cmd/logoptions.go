@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultLogTailLines is the number of lines requested when a stream starts
+const DefaultLogTailLines = 1000
+
+// LogOlderLinesStep is the number of additional lines requested per container
+// when older lines are loaded on demand
+const LogOlderLinesStep = 1000
+
+// logSinceDurations are the since-durations cycled through in the log view, zero for no limit
+var logSinceDurations = []time.Duration{0, 5 * time.Minute, time.Hour, 24 * time.Hour}
+
+// logTailLengths are the tail lengths cycled through in the log view, zero for the whole log
+var logTailLengths = []int64{100, 500, DefaultLogTailLines, 5000, 0}
+
+// logOptions are the request and display options chosen in the log view.
+// They are kept for the whole session and apply to every stream opened.
+type logOptions struct {
+	timestamps bool          // Show server timestamps in front of lines
+	utc        bool          // Show timestamps in UTC instead of local time
+	since      time.Duration // Only request lines newer than this, zero for no limit
+	tailLines  int64         // Number of lines requested initially, zero for the whole log
+	raw        bool          // Show JSON and logfmt lines as logged
+	minLevel   logLevel      // Hide structured lines below this level
+}
+
+// defaultLogOptions returns the options used until the user changes them
+func defaultLogOptions() logOptions {
+	return logOptions{tailLines: DefaultLogTailLines}
+}
+
+// apply sets the initial request limits on the pod log options
+func (o logOptions) apply(podLogOpts *corev1.PodLogOptions) {
+	if o.tailLines > 0 {
+		tailLines := o.tailLines
+		podLogOpts.TailLines = &tailLines
+	}
+	if o.since > 0 {
+		sinceSeconds := int64(o.since.Seconds())
+		podLogOpts.SinceSeconds = &sinceSeconds
+	}
+}
+
+// location returns the time zone timestamps are shown in
+func (o logOptions) location() *time.Location {
+	if o.utc {
+		return time.UTC
+	}
+	return time.Local
+}
+
+// String summarizes the options for the help bar
+func (o logOptions) String() string {
+	parts := []string{"tail all"}
+	if o.tailLines > 0 {
+		parts[0] = fmt.Sprintf("tail %d", o.tailLines)
+	}
+	if o.since > 0 {
+		parts = append(parts, "since "+formatLogDuration(o.since))
+	}
+	if o.timestamps {
+		if o.utc {
+			parts = append(parts, "UTC")
+		} else {
+			parts = append(parts, "local time")
+		}
+	}
+	if o.raw {
+		parts = append(parts, "raw")
+	}
+	if o.minLevel != logLevelNone {
+		parts = append(parts, "level ≥ "+o.minLevel.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatLogDuration formats a since-duration without trailing zero units
+func formatLogDuration(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return d.String()
+}
+
+// nextSince returns the since-duration following the current one
+func (o logOptions) nextSince() time.Duration {
+	for i, since := range logSinceDurations {
+		if since == o.since {
+			return logSinceDurations[(i+1)%len(logSinceDurations)]
+		}
+	}
+	return logSinceDurations[0]
+}
+
+// stepTail returns the next longer or shorter tail length
+func (o logOptions) stepTail(longer bool) int64 {
+	index := len(logTailLengths) - 1
+	for i, tailLines := range logTailLengths {
+		if tailLines == o.tailLines {
+			index = i
+			break
+		}
+	}
+	if longer && index < len(logTailLengths)-1 {
+		index++
+	} else if !longer && index > 0 {
+		index--
+	}
+	return logTailLengths[index]
+}
+
+// ToggleTimestamps shows or hides server timestamps
+func (l *LogView) ToggleTimestamps() {
+	l.options.timestamps = !l.options.timestamps
+	l.applyDisplayOptions()
+}
+
+// ToggleUTC switches timestamps between local time and UTC
+func (l *LogView) ToggleUTC() {
+	l.options.utc = !l.options.utc
+	if !l.options.timestamps {
+		// Changing the time zone of hidden timestamps would have no visible effect
+		l.options.timestamps = true
+	}
+	l.applyDisplayOptions()
+}
+
+// ToggleRaw switches between pretty-printed and raw structured lines
+func (l *LogView) ToggleRaw() {
+	l.options.raw = !l.options.raw
+	l.applyDisplayOptions()
+}
+
+// CycleMinLevel raises the minimum level of structured lines shown, wrapping around to all levels
+func (l *LogView) CycleMinLevel() {
+	l.options.minLevel = l.options.minLevel.next()
+	l.applyDisplayOptions()
+}
+
+// CycleSince switches to the next since-duration and restarts the streams
+func (l *LogView) CycleSince() {
+	l.options.since = l.options.nextSince()
+	l.restartWithOptions()
+}
+
+// StepTail changes the tail length and restarts the streams
+func (l *LogView) StepTail(longer bool) {
+	tailLines := l.options.stepTail(longer)
+	if tailLines == l.options.tailLines {
+		return
+	}
+	l.options.tailLines = tailLines
+	l.restartWithOptions()
+}
+
+// applyDisplayOptions redraws the buffered lines with the current display options
+func (l *LogView) applyDisplayOptions() {
+	l.buffer.SetTimestamps(l.options.timestamps, l.options.location())
+	l.buffer.SetStructured(l.options.raw, l.options.minLevel)
+	l.currentMatch = -1
+	l.buffer.Render(l.textView)
+	if l.autoScroll {
+		l.textView.ScrollToEnd()
+	}
+	l.updateHelpBar()
+}
+
+// restartWithOptions restarts the streams so new request options take effect
+func (l *LogView) restartWithOptions() {
+	if (l.pod == nil && l.selector == nil) || l.client == nil {
+		l.updateHelpBar()
+		return
+	}
+	l.startStreams()
+}
+
+// LoadOlder fetches lines older than the oldest buffered line of every stream
+// in the background and inserts them at the top of the buffer
+func (l *LogView) LoadOlder() {
+	if l.client == nil || l.streamCtx == nil {
+		return
+	}
+	if l.options.tailLines == 0 && l.options.since == 0 {
+		l.setStatus("[yellow]The whole log is already loaded")
+		return
+	}
+	l.setStatus("[yellow]Loading older lines…")
+	go l.loadOlder(l.streamCtx, l.buffer.Generation(), l.previous, l.options.since, l.streamsStarted)
+}
+
+// loadOlder requests more lines from every stream without following and keeps
+// those older than what is already buffered
+func (l *LogView) loadOlder(ctx context.Context, generation int, previous bool, since time.Duration, started time.Time) {
+	stats := l.buffer.TargetStats()
+	var older []logLine
+	var loadErr error
+	for target, info := range l.streamInfos() {
+		stat := stats[target]
+		cutoff := stat.oldest
+		if cutoff.IsZero() {
+			if since == 0 {
+				continue // The container has no output at all
+			}
+			// Nothing was logged within the since window
+			cutoff = started.Add(-since)
+		}
+
+		tailLines := int64(stat.count + LogOlderLinesStep)
+		podLogOpts := &corev1.PodLogOptions{
+			Container:  target.Container,
+			Previous:   previous,
+			Timestamps: true,
+			TailLines:  &tailLines,
+		}
+		reqCtx, cancel := context.WithTimeout(ctx, APITimeout)
+		data, err := l.client.Clientset.CoreV1().Pods(target.Namespace).GetLogs(target.Pod, podLogOpts).DoRaw(reqCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			loadErr = err
+			continue
+		}
+
+		for _, raw := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+			line := parseLogLine(strings.TrimRight(raw, "\r"))
+			if line.timestamp.IsZero() || !line.timestamp.Before(cutoff) {
+				continue
+			}
+			line.target, line.source, line.color = target, info.source, info.color
+			older = append(older, line)
+		}
+	}
+
+	// Interleave lines of different containers by time like the live streams
+	sort.SliceStable(older, func(i, j int) bool {
+		return older[i].timestamp.Before(older[j].timestamp)
+	})
+
+	if l.app == nil {
+		return
+	}
+	l.app.QueueUpdateDraw(func() {
+		if ctx.Err() != nil {
+			return
+		}
+		added, full := l.buffer.Prepend(generation, older)
+		switch {
+		case loadErr != nil:
+			l.status = fmt.Sprintf("[red]Loading older lines failed: %s", tview.Escape(loadErr.Error()))
+		case full:
+			l.status = fmt.Sprintf("[yellow]Log buffer is full (%d lines), increase --log-max-lines to load more", l.buffer.Len())
+		case added == 0:
+			l.status = "[yellow]No older lines"
+		default:
+			l.status = fmt.Sprintf("[green]Loaded %d older lines", added)
+		}
+		if added > 0 {
+			l.currentMatch = -1
+			l.autoScroll = false
+			l.buffer.Render(l.textView)
+			l.textView.ScrollToBeginning()
+		}
+		l.updateHelpBar()
+	})
+}
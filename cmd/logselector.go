@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PodSelector selects the pods streamed together in aggregated log mode
+type PodSelector struct {
+	Description       string
+	Namespace         string // Empty for all namespaces
+	LabelSelector     string
+	FieldSelector     string
+	NameRegex         *regexp.Regexp
+	IncludeNamespaces map[string]bool
+	ExcludeNamespaces map[string]bool
+}
+
+// ParsePodSelector parses user input into a PodSelector. Input starting with
+// "~" is a regular expression matched against pod names, anything else is a
+// Kubernetes label selector such as "app=web,tier!=db".
+func ParsePodSelector(input string) (PodSelector, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return PodSelector{}, fmt.Errorf("empty selector")
+	}
+
+	if strings.HasPrefix(input, "~") {
+		re, err := regexp.Compile(input[1:])
+		if err != nil {
+			return PodSelector{}, fmt.Errorf("invalid pod name regex: %v", err)
+		}
+		return PodSelector{
+			Description: fmt.Sprintf("pods matching /%s/", input[1:]),
+			NameRegex:   re,
+		}, nil
+	}
+
+	if _, err := labels.Parse(input); err != nil {
+		return PodSelector{}, fmt.Errorf("invalid label selector: %v", err)
+	}
+	return PodSelector{
+		Description:   fmt.Sprintf("pods with labels %s", input),
+		LabelSelector: input,
+	}, nil
+}
+
+// Resolve lists the running pods matching the selector and returns a log
+// target for each of their containers, sorted by pod and container name
+func (s PodSelector) Resolve(ctx context.Context, client *KubeClientWrapper) ([]logTarget, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, APITimeout)
+	defer cancel()
+
+	pods, err := client.Clientset.CoreV1().Pods(s.Namespace).List(reqCtx, metav1.ListOptions{
+		LabelSelector: s.LabelSelector,
+		FieldSelector: s.FieldSelector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+
+	var targets []logTarget
+	for _, pod := range pods.Items {
+		if s.ExcludeNamespaces[pod.Namespace] {
+			continue
+		}
+		if len(s.IncludeNamespaces) > 0 && !s.IncludeNamespaces[pod.Namespace] {
+			continue
+		}
+		if s.NameRegex != nil && !s.NameRegex.MatchString(pod.Name) {
+			continue
+		}
+		// Only running pods have output to follow
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			targets = append(targets, logTarget{
+				Namespace: pod.Namespace,
+				Pod:       pod.Name,
+				Container: container.Name,
+			})
+		}
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Namespace != targets[j].Namespace {
+			return targets[i].Namespace < targets[j].Namespace
+		}
+		if targets[i].Pod != targets[j].Pod {
+			return targets[i].Pod < targets[j].Pod
+		}
+		return targets[i].Container < targets[j].Container
+	})
+	return targets, nil
+}
+
+// followSelector keeps one stream per matching container running, adding
+// streams for pods that appear and stopping those of pods that disappear.
+// New streams are requested with the previous flag and options given.
+func (l *LogView) followSelector(ctx context.Context, generation int, selector PodSelector, previous bool, options logOptions) {
+	streams := make(map[logTarget]context.CancelFunc)
+	podColors := make(map[string]string)
+	ticker := time.NewTicker(RefreshInterval)
+	defer ticker.Stop()
+
+	for first := true; ; first = false {
+		targets, err := selector.Resolve(ctx, l.client)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			l.appendLine(generation, logLine{text: err.Error(), note: true})
+		} else {
+			current := make(map[logTarget]bool)
+			for _, target := range targets {
+				current[target] = true
+				if _, exists := streams[target]; exists {
+					continue
+				}
+
+				// Lines of the same pod share a color
+				podKey := target.Namespace + "/" + target.Pod
+				color, ok := podColors[podKey]
+				if !ok {
+					color = logPrefixColors[len(podColors)%len(logPrefixColors)]
+					podColors[podKey] = color
+				}
+				source := target.Pod + "/" + target.Container
+
+				if !first {
+					l.appendLine(generation, logLine{source: source, color: color, text: "Pod joined the stream", note: true})
+				}
+				streamCtx, cancel := context.WithCancel(ctx)
+				streams[target] = cancel
+				l.setStreamState(streamCtx, target, LogStreamStreaming)
+				go l.streamLogs(streamCtx, generation, target, source, color, previous, options)
+			}
+
+			for target, cancel := range streams {
+				if current[target] {
+					continue
+				}
+				cancel()
+				delete(streams, target)
+				l.clearStreamState(ctx, target)
+				l.appendLine(generation, logLine{
+					source: target.Pod + "/" + target.Container,
+					color:  podColors[target.Namespace+"/"+target.Pod],
+					text:   "Pod left the stream",
+					note:   true,
+				})
+			}
+
+			if len(targets) == 0 && first {
+				l.appendLine(generation, logLine{text: "No running pods match " + selector.Description, note: true})
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
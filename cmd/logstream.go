@@ -0,0 +1,319 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Log stream reconnect backoff
+const (
+	LogReconnectMinBackoff = 1 * time.Second
+	LogReconnectMaxBackoff = 30 * time.Second
+)
+
+// Log stream states shown in the log view title
+const (
+	LogStreamStreaming    = "streaming"
+	LogStreamReconnecting = "reconnecting"
+	LogStreamEnded        = "ended"
+)
+
+// logStreamInfo is the state of a stream and the prefix of its lines
+type logStreamInfo struct {
+	state  string
+	source string
+	color  string
+}
+
+// streamLogs streams logs from a single container, reconnecting with backoff
+// when the stream breaks while the container is still expected to run.
+// previous and options are copies of the view's, which only the UI goroutine
+// may read.
+func (l *LogView) streamLogs(ctx context.Context, generation int, target logTarget, source, color string, previous bool, options logOptions) {
+	defer l.setStreamState(ctx, target, LogStreamEnded)
+	l.registerStream(ctx, target, source, color)
+
+	var lastTime time.Time // Timestamp of the last line received
+	seenAtLast := 0        // Lines received with exactly lastTime, to skip on reconnect
+	backoff := LogReconnectMinBackoff
+
+	for {
+		podLogOpts := &corev1.PodLogOptions{
+			Container:  target.Container,
+			Follow:     !previous, // A terminated instance has no more output to follow
+			Previous:   previous,
+			Timestamps: true, // Used to resume without duplicates after a reconnect
+		}
+		if lastTime.IsZero() {
+			options.apply(podLogOpts) // Start from the end of logs
+		} else {
+			// SinceTime has second precision, so lines already seen are skipped below
+			podLogOpts.SinceTime = &metav1.Time{Time: lastTime}
+		}
+
+		var streamErr error
+		req := l.client.Clientset.CoreV1().Pods(target.Namespace).GetLogs(target.Pod, podLogOpts)
+		stream, err := req.Stream(ctx)
+		if err != nil {
+			streamErr = err
+		} else {
+			l.setStreamState(ctx, target, LogStreamStreaming)
+			skip := 0
+			if !lastTime.IsZero() {
+				skip = seenAtLast
+			}
+
+			reader := bufio.NewReader(stream)
+			for {
+				raw, err := reader.ReadString('\n')
+				if ctx.Err() != nil {
+					stream.Close()
+					return
+				}
+				if len(raw) > 0 {
+					line := parseLogLine(strings.TrimRight(raw, "\r\n"))
+					line.target, line.source, line.color = target, source, color
+
+					// Drop lines that were already received before reconnecting
+					if !line.timestamp.IsZero() && !lastTime.IsZero() {
+						if line.timestamp.Before(lastTime) {
+							continue
+						}
+						if line.timestamp.Equal(lastTime) && skip > 0 {
+							skip--
+							continue
+						}
+					}
+					if !line.timestamp.IsZero() {
+						if line.timestamp.Equal(lastTime) {
+							seenAtLast++
+						} else {
+							lastTime = line.timestamp
+							seenAtLast = 1
+						}
+					}
+					backoff = LogReconnectMinBackoff
+					l.appendLine(generation, line)
+				}
+				if err != nil {
+					if err != io.EOF {
+						streamErr = err
+					}
+					break
+				}
+			}
+			stream.Close()
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Previous instance logs are complete once read
+		if previous {
+			if streamErr != nil {
+				l.appendLine(generation, logLine{source: source, color: color, text: fmt.Sprintf("Error getting pod logs: %v", streamErr), note: true})
+			}
+			return
+		}
+
+		if ended, reason := l.containerFinished(ctx, target); ended {
+			if streamErr != nil {
+				l.appendLine(generation, logLine{source: source, color: color, text: fmt.Sprintf("Error getting pod logs: %v", streamErr), note: true})
+			}
+			l.appendLine(generation, logLine{source: source, color: color, text: "Stream ended: " + reason, note: true})
+			return
+		}
+
+		// The container is still expected to produce output, so reconnect
+		l.setStreamState(ctx, target, LogStreamReconnecting)
+		if streamErr != nil {
+			l.appendLine(generation, logLine{source: source, color: color, text: fmt.Sprintf("Log stream interrupted: %v (reconnecting in %s)", streamErr, backoff), note: true})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > LogReconnectMaxBackoff {
+			backoff = LogReconnectMaxBackoff
+		}
+	}
+}
+
+// containerFinished reports whether a container will not produce more output,
+// either because its pod is gone or because it terminated and won't restart
+func (l *LogView) containerFinished(ctx context.Context, target logTarget) (bool, string) {
+	reqCtx, cancel := context.WithTimeout(ctx, APITimeout)
+	defer cancel()
+
+	pod, err := l.client.Clientset.CoreV1().Pods(target.Namespace).Get(reqCtx, target.Pod, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, "pod was deleted"
+		}
+		return false, "" // Transient API error, keep trying
+	}
+	if pod.DeletionTimestamp != nil {
+		return true, "pod is terminating"
+	}
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return true, fmt.Sprintf("pod %s", strings.ToLower(string(pod.Status.Phase)))
+	}
+
+	// Init containers run once, unless they are sidecars restarting like regular containers
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.Name == target.Container && status.State.Terminated != nil && !isSidecar(pod, status.Name) {
+			return true, "init container " + terminationReason(status.State.Terminated)
+		}
+	}
+
+	// Ephemeral containers are never restarted
+	for _, status := range pod.Status.EphemeralContainerStatuses {
+		if status.Name == target.Container && status.State.Terminated != nil {
+			return true, "ephemeral container " + terminationReason(status.State.Terminated)
+		}
+	}
+
+	// Regular containers are restarted according to the restart policy of the pod
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != target.Container || status.State.Terminated == nil {
+			continue
+		}
+		switch terminated := status.State.Terminated; pod.Spec.RestartPolicy {
+		case corev1.RestartPolicyNever:
+			return true, "container " + terminationReason(terminated)
+		case corev1.RestartPolicyOnFailure:
+			if terminated.ExitCode == 0 {
+				return true, "container " + terminationReason(terminated)
+			}
+		}
+	}
+	return false, ""
+}
+
+// isSidecar reports whether an init container of a pod restarts like a regular container
+func isSidecar(pod *corev1.Pod, name string) bool {
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name == name {
+			return container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways
+		}
+	}
+	return false
+}
+
+// terminationReason describes how a container terminated
+func terminationReason(terminated *corev1.ContainerStateTerminated) string {
+	return TerminationInfo{
+		Reason:   terminated.Reason,
+		ExitCode: terminated.ExitCode,
+	}.String()
+}
+
+// parseLogLine splits the RFC3339 timestamp added by the API server from a log line
+func parseLogLine(raw string) logLine {
+	if idx := strings.IndexByte(raw, ' '); idx > 0 {
+		if timestamp, err := time.Parse(time.RFC3339Nano, raw[:idx]); err == nil {
+			text := raw[idx+1:]
+			return logLine{text: text, timestamp: timestamp, structured: parseStructuredLog(text)}
+		}
+	}
+	return logLine{text: raw, structured: parseStructuredLog(raw)}
+}
+
+// registerStream records the prefix lines of a stream are shown with
+func (l *LogView) registerStream(ctx context.Context, target logTarget, source, color string) {
+	if ctx.Err() != nil {
+		return
+	}
+	l.stateMu.Lock()
+	defer l.stateMu.Unlock()
+	info := l.streamStates[target]
+	info.source, info.color = source, color
+	l.streamStates[target] = info
+}
+
+// streamInfos returns a copy of the state of every stream
+func (l *LogView) streamInfos() map[logTarget]logStreamInfo {
+	l.stateMu.Lock()
+	defer l.stateMu.Unlock()
+	infos := make(map[logTarget]logStreamInfo, len(l.streamStates))
+	for target, info := range l.streamStates {
+		infos[target] = info
+	}
+	return infos
+}
+
+// setStreamState records the state of a stream and updates the title
+func (l *LogView) setStreamState(ctx context.Context, target logTarget, state string) {
+	if ctx.Err() != nil {
+		return
+	}
+	l.stateMu.Lock()
+	info := l.streamStates[target]
+	info.state = state
+	l.streamStates[target] = info
+	l.stateMu.Unlock()
+
+	if l.app != nil {
+		l.app.QueueUpdateDraw(func() {
+			if ctx.Err() == nil {
+				l.updateTitle()
+			}
+		})
+	}
+}
+
+// clearStreamState forgets a stream that is no longer followed
+func (l *LogView) clearStreamState(ctx context.Context, target logTarget) {
+	l.stateMu.Lock()
+	delete(l.streamStates, target)
+	l.stateMu.Unlock()
+
+	if l.app != nil {
+		l.app.QueueUpdateDraw(func() {
+			if ctx.Err() == nil {
+				l.updateTitle()
+			}
+		})
+	}
+}
+
+// streamCount returns the number of streams being followed
+func (l *LogView) streamCount() int {
+	l.stateMu.Lock()
+	defer l.stateMu.Unlock()
+	return len(l.streamStates)
+}
+
+// streamStatus summarizes the state of all streams for the title
+func (l *LogView) streamStatus() string {
+	l.stateMu.Lock()
+	defer l.stateMu.Unlock()
+
+	if len(l.streamStates) == 0 {
+		return ""
+	}
+	ended := 0
+	for _, info := range l.streamStates {
+		switch info.state {
+		case LogStreamReconnecting:
+			return "reconnecting…"
+		case LogStreamEnded:
+			ended++
+		}
+	}
+	if ended == len(l.streamStates) {
+		return "stream ended"
+	}
+	return ""
+}
@@ -1,28 +1,65 @@
 package cmd
 
 import (
-	"bufio"
 	"context"
 	"fmt"
-	"io"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
-	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// logPrefixColors are used to tell apart interleaved lines from different containers
+var logPrefixColors = []string{"aqua", "yellow", "fuchsia", "lime", "orange", "skyblue", "violet", "teal"}
+
+// allContainersLabel is the picker entry that streams every container of the pod
+const allContainersLabel = "All containers"
+
+// logTarget identifies a single container log stream
+type logTarget struct {
+	Namespace string
+	Pod       string
+	Container string
+}
+
 // LogView represents a full-screen log streaming view
 type LogView struct {
-	textView      *tview.TextView
-	flex          *tview.Flex
-	pod           *PodInfo
-	stopChan      chan struct{}
-	app           *tview.Application
-	previousApp   tview.Primitive
-	previousTable *tview.Table
-	previousRow   int
-	mainApp       *App // Add reference to main app
-	autoScroll    bool
+	textView       *tview.TextView
+	helpBar        *tview.TextView
+	input          *tview.InputField
+	inputMode      string // "search" or "grep" while the input field is shown
+	inputPrevious  string // Query to restore when input is cancelled
+	picker         *tview.List
+	pages          *tview.Pages
+	flex           *tview.Flex
+	pod            *PodInfo
+	selector       *PodSelector // Set when streaming all pods matching a selector
+	client         *KubeClientWrapper
+	container      string // Selected container, empty when streaming all containers
+	previous       bool   // Show logs of the previous terminated instance
+	cancel         context.CancelFunc
+	app            *tview.Application
+	previousApp    tview.Primitive
+	previousTable  *tview.Table
+	previousRow    int
+	mainApp        *App // Add reference to main app
+	autoScroll     bool
+	doneFunc       func()
+	buffer         logBuffer
+	stateMu        sync.Mutex
+	streamStates   map[logTarget]logStreamInfo // State of each running stream
+	streamCtx      context.Context             // Context of the running streams
+	streamsStarted time.Time                   // When the running streams were started
+	options        logOptions                  // Request and display options kept for the session
+	exportDir      string
+	recorder       logRecorder
+	status         string // Result of the last save or record action
+	searchQuery    string
+	grepQuery      string
+	currentMatch   int
 }
 
 // NewLogView creates a new LogView instance
@@ -33,22 +70,73 @@ func NewLogView() *LogView {
 			SetScrollable(true).
 			SetWrap(true).
 			SetTextColor(tcell.ColorSkyblue),
-		stopChan:   make(chan struct{}),
-		autoScroll: true,
+		helpBar: tview.NewTextView().
+			SetDynamicColors(true).
+			SetTextColor(tcell.ColorGray),
+		input: tview.NewInputField().
+			SetFieldBackgroundColor(tcell.ColorBlack).
+			SetLabelColor(tcell.ColorYellow),
+		picker: tview.NewList().
+			ShowSecondaryText(false),
+		pages:        tview.NewPages(),
+		autoScroll:   true,
+		streamStates: make(map[logTarget]logStreamInfo),
+		options:      defaultLogOptions(),
 	}
 
+	// Add border with title
+	logView.textView.SetBorder(true)
+	logView.textView.SetTitle(" Pod Logs ")
+	logView.textView.SetRegions(true)
+	logView.buffer.SetTimestamps(logView.options.timestamps, logView.options.location())
+	logView.updateHelpBar()
+
+	// Search and grep queries are applied while typing
+	logView.input.SetChangedFunc(func(text string) {
+		logView.applyQuery(logView.inputMode, text)
+	})
+	logView.input.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			logView.applyQuery(logView.inputMode, logView.inputPrevious)
+		}
+		logView.hideInput()
+		if key == tcell.KeyEnter && logView.inputMode == "search" {
+			logView.NextMatch(true)
+		}
+	})
+
+	// Set up the container picker as a centered overlay
+	logView.picker.SetBorder(true).
+		SetTitle(" Select Container ").
+		SetBorderColor(tcell.ColorGray)
+	logView.picker.SetDoneFunc(func() {
+		logView.hidePicker()
+	})
+
+	logView.pages.AddPage("logs", logView.textView, true, true)
+
 	// Create a flex container for the log view
 	logView.flex = tview.NewFlex().
 		SetDirection(tview.FlexRow).
-		AddItem(logView.textView, 0, 1, true)
-
-	// Add border with title and instructions
-	logView.textView.SetBorder(true)
-	logView.textView.SetTitle(" Pod Logs (Press Esc to exit, ↑/↓ to scroll, Space to toggle auto-scroll) ")
+		AddItem(logView.pages, 0, 1, true).
+		AddItem(logView.helpBar, 1, 0, false)
 
 	// Set up input handling for the text view
 	logView.textView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		switch event.Key() {
+		case tcell.KeyEscape:
+			// Clear an active search or filter before leaving the view
+			if logView.searchQuery != "" || logView.grepQuery != "" {
+				logView.applyQuery("search", "")
+				logView.applyQuery("grep", "")
+				return nil
+			}
+			logView.Stop()
+			logView.stopRecording()
+			if logView.doneFunc != nil {
+				logView.doneFunc()
+			}
+			return nil
 		case tcell.KeyUp:
 			logView.autoScroll = false
 			row, _ := logView.textView.GetScrollOffset()
@@ -70,12 +158,74 @@ func NewLogView() *LogView {
 			logView.textView.ScrollTo(row+10, 0)
 			return nil
 		case tcell.KeyRune:
-			if event.Rune() == ' ' {
+			switch event.Rune() {
+			case ' ':
 				logView.autoScroll = !logView.autoScroll
 				if logView.autoScroll {
 					logView.textView.ScrollToEnd()
 				}
 				return nil
+			case 'c':
+				logView.showPicker()
+				return nil
+			case 'p':
+				logView.TogglePrevious()
+				return nil
+			case '/':
+				logView.showInput("search")
+				return nil
+			case 'g':
+				logView.showInput("grep")
+				return nil
+			case 'n':
+				logView.NextMatch(true)
+				return nil
+			case 'N':
+				logView.NextMatch(false)
+				return nil
+			case 's', 'z':
+				if path, err := logView.SaveBuffer(event.Rune() == 'z'); err != nil {
+					logView.setStatus(fmt.Sprintf("[red]Save failed: %s", tview.Escape(err.Error())))
+				} else {
+					logView.setStatus(fmt.Sprintf("[green]Saved to %s", tview.Escape(path)))
+				}
+				return nil
+			case 't':
+				logView.ToggleTimestamps()
+				return nil
+			case 'u':
+				logView.ToggleUTC()
+				return nil
+			case 'w':
+				logView.CycleSince()
+				return nil
+			case '+', '=':
+				logView.StepTail(true)
+				return nil
+			case '-':
+				logView.StepTail(false)
+				return nil
+			case 'o':
+				logView.LoadOlder()
+				return nil
+			case 'j':
+				logView.ToggleRaw()
+				return nil
+			case 'L':
+				logView.CycleMinLevel()
+				return nil
+			case 'R':
+				path, err := logView.ToggleRecording()
+				switch {
+				case err != nil:
+					logView.setStatus(fmt.Sprintf("[red]Recording failed: %s", tview.Escape(err.Error())))
+				case logView.IsRecording():
+					logView.setStatus(fmt.Sprintf("[red]Recording to %s", tview.Escape(path)))
+				default:
+					logView.setStatus(fmt.Sprintf("[green]Recorded to %s", tview.Escape(path)))
+				}
+				logView.updateTitle()
+				return nil
 			}
 		}
 		return event
@@ -105,6 +255,21 @@ func (l *LogView) SetPreviousSelection(table *tview.Table, row int) {
 	l.previousRow = row
 }
 
+// SetMaxLines sets the number of lines kept in the log buffer
+func (l *LogView) SetMaxLines(maxLines int) {
+	l.buffer.SetMaxLines(maxLines)
+}
+
+// SetExportDir sets the directory saved and recorded logs are written to
+func (l *LogView) SetExportDir(dir string) {
+	l.exportDir = dir
+}
+
+// SetDoneFunc sets the handler called when the user closes the log view
+func (l *LogView) SetDoneFunc(handler func()) {
+	l.doneFunc = handler
+}
+
 // GetFlex returns the flex container
 func (l *LogView) GetFlex() *tview.Flex {
 	return l.flex
@@ -112,66 +277,396 @@ func (l *LogView) GetFlex() *tview.Flex {
 
 // ShowPodLogs displays logs for the specified pod
 func (l *LogView) ShowPodLogs(k8s *KubeClientWrapper, podInfo *PodInfo) {
+	l.ShowContainerLogs(k8s, podInfo, "")
+}
+
+// ShowContainerLogs displays logs for a container of the pod, or lets the user
+// pick one if container is empty and the pod has several
+func (l *LogView) ShowContainerLogs(k8s *KubeClientWrapper, podInfo *PodInfo, container string) {
+	// Stop any existing log stream
+	l.Stop()
+	l.stopRecording()
+	l.status = ""
+
 	l.pod = podInfo
+	l.selector = nil
+	l.client = k8s
+	l.container = ""
+	l.previous = false
+	l.autoScroll = true
+	l.buffer.Reset()
 	l.textView.Clear()
-	l.textView.SetTitle(fmt.Sprintf(" Pod Logs: %s/%s (Press Esc to exit, ↑/↓ to scroll, Space to toggle auto-scroll) ", podInfo.Namespace, podInfo.Name))
+	l.hidePicker()
+	l.updateTitle()
 
-	// Stop any existing log stream
-	if l.stopChan != nil {
-		close(l.stopChan)
+	if k8s == nil {
+		l.textView.SetText("[red]Log streaming requires a connection to a real cluster.")
+		return
+	}
+
+	containers := podInfo.GetContainers()
+	if _, ok := podInfo.GetContainerInfo(container); ok {
+		l.container = container
+		l.startStreams()
+		return
 	}
-	l.stopChan = make(chan struct{})
+	if len(containers) > 1 {
+		// Let the user pick which container to follow
+		l.textView.SetText("[gray]Select a container, or press c to open the container list.")
+		l.showPicker()
+		return
+	}
+	if len(containers) == 1 {
+		l.container = containers[0].Name
+	}
+	l.startStreams()
+}
+
+// ShowSelectorLogs displays interleaved logs of all pods matching the selector
+func (l *LogView) ShowSelectorLogs(k8s *KubeClientWrapper, selector PodSelector) {
+	l.Stop()
+	l.stopRecording()
+	l.status = ""
+
+	l.pod = nil
+	l.selector = &selector
+	l.client = k8s
+	l.container = ""
+	l.previous = false
 	l.autoScroll = true
+	l.buffer.Reset()
+	l.textView.Clear()
+	l.hidePicker()
+	l.updateTitle()
 
-	// Start streaming logs
-	go l.streamLogs(k8s, podInfo)
+	if k8s == nil {
+		l.textView.SetText("[red]Log streaming requires a connection to a real cluster.")
+		return
+	}
+	l.startStreams()
 }
 
-// streamLogs continuously streams logs from the pod
-func (l *LogView) streamLogs(k8s *KubeClientWrapper, podInfo *PodInfo) {
-	podLogOpts := &corev1.PodLogOptions{
-		Follow:    true,
-		TailLines: new(int64), // Start from the end of logs
+// showPicker displays the container picker overlay
+func (l *LogView) showPicker() {
+	if l.pod == nil || l.client == nil {
+		return
+	}
+	containers := l.pod.GetContainers()
+	if len(containers) < 2 {
+		return
 	}
-	*podLogOpts.TailLines = 1000 // Show last 1000 lines initially
 
-	req := k8s.Clientset.CoreV1().Pods(podInfo.Namespace).GetLogs(podInfo.Name, podLogOpts)
-	stream, err := req.Stream(context.Background())
-	if err != nil {
-		l.textView.Write([]byte(fmt.Sprintf("[red]Error getting pod logs: %v", err)))
+	l.picker.Clear()
+	l.picker.AddItem(allContainersLabel, "", 0, func() {
+		l.selectContainer("")
+	})
+	current := 0
+	for i, container := range containers {
+		name := container.Name
+		if name == l.container {
+			current = i + 1
+		}
+		l.picker.AddItem(tview.Escape(container.Label()), "", 0, func() {
+			l.selectContainer(name)
+		})
+	}
+	l.picker.SetCurrentItem(current)
+
+	// Center the picker on top of the logs
+	height := len(containers) + 3
+	frame := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(l.picker, height, 0, true).
+			AddItem(nil, 0, 1, false), 50, 0, true).
+		AddItem(nil, 0, 1, false)
+	l.pages.AddPage("picker", frame, true, true)
+	if l.app != nil {
+		l.app.SetFocus(l.picker)
+	}
+}
+
+// hidePicker removes the container picker overlay
+func (l *LogView) hidePicker() {
+	if !l.pages.HasPage("picker") {
+		return
+	}
+	l.pages.RemovePage("picker")
+	if l.app != nil {
+		l.app.SetFocus(l.textView)
+	}
+}
+
+// selectContainer switches the stream to the given container, or to all containers if empty
+func (l *LogView) selectContainer(container string) {
+	l.hidePicker()
+	l.container = container
+	l.startStreams()
+}
+
+// TogglePrevious switches between logs of the current and the previous container instance
+func (l *LogView) TogglePrevious() {
+	if (l.pod == nil && l.selector == nil) || l.client == nil {
+		return
+	}
+	l.previous = !l.previous
+	l.startStreams()
+}
+
+// updateTitle refreshes the border title with the current pod, container and instance
+func (l *LogView) updateTitle() {
+	if l.selector != nil {
+		title := fmt.Sprintf(" Logs: %s (%d containers) ", tview.Escape(l.selector.Description), l.streamCount())
+		if l.previous {
+			title += "- previous instances "
+		}
+		if status := l.streamStatus(); status == "reconnecting…" {
+			title += "[yellow]" + status + "[-] "
+		}
+		if l.IsRecording() {
+			title += "[red]● REC[-] "
+		}
+		l.textView.SetTitle(title)
+		return
+	}
+	if l.pod == nil {
+		l.textView.SetTitle(" Pod Logs ")
 		return
 	}
-	defer stream.Close()
+	container := l.container
+	if container == "" && len(l.pod.GetContainers()) > 1 {
+		container = "all containers"
+	}
+	title := fmt.Sprintf(" Pod Logs: %s/%s ", l.pod.Namespace, l.pod.Name)
+	if container != "" {
+		title += tview.Escape(fmt.Sprintf("[%s] ", container))
+	}
+
+	// Show how the last instance terminated
+	var terminations []string
+	for _, ref := range l.pod.GetContainers() {
+		if l.container != "" && ref.Name != l.container {
+			continue
+		}
+		info, _ := l.pod.GetContainerInfo(ref.Name)
+		if info.LastTermination == nil {
+			continue
+		}
+		if l.container != "" {
+			terminations = append(terminations, info.LastTermination.String())
+		} else {
+			terminations = append(terminations, fmt.Sprintf("%s: %s", ref.Name, info.LastTermination))
+		}
+	}
+	if l.previous {
+		title += "- previous instance "
+		if len(terminations) > 0 {
+			title += fmt.Sprintf("(%s) ", tview.Escape(strings.Join(terminations, "; ")))
+		}
+	} else if len(terminations) > 0 {
+		title += fmt.Sprintf("- last terminated: %s ", tview.Escape(strings.Join(terminations, "; ")))
+	}
 
-	reader := bufio.NewReader(stream)
-	for {
-		select {
-		case <-l.stopChan:
+	switch status := l.streamStatus(); status {
+	case "reconnecting…":
+		title += "[yellow]" + status + "[-] "
+	case "stream ended":
+		title += "[red]" + status + "[-] "
+	}
+	if l.IsRecording() {
+		title += "[red]● REC[-] "
+	}
+	l.textView.SetTitle(title)
+}
+
+// refreshPodStatus fetches the latest pod status so the title shows current termination details
+func (l *LogView) refreshPodStatus(ctx context.Context, namespace, name string) {
+	reqCtx, cancel := context.WithTimeout(ctx, APITimeout)
+	defer cancel()
+
+	pod, err := l.client.Clientset.CoreV1().Pods(namespace).Get(reqCtx, name, metav1.GetOptions{})
+	if err != nil || ctx.Err() != nil || l.app == nil {
+		return
+	}
+	podInfo := GetPodInfo(pod)
+	l.app.QueueUpdateDraw(func() {
+		if ctx.Err() != nil {
 			return
-		default:
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				if err != io.EOF {
-					l.textView.Write([]byte(fmt.Sprintf("[red]Error reading logs: %v\n", err)))
-				}
-				return
-			}
+		}
+		l.pod = &podInfo
+		l.updateTitle()
+	})
+}
+
+// startStreams (re)starts streaming for the selected container or all containers
+func (l *LogView) startStreams() {
+	l.Stop()
+	generation := l.buffer.Reset()
+	l.textView.Clear()
+	l.currentMatch = -1
+	l.autoScroll = true
+	l.updateTitle()
+	l.updateHelpBar()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+	l.streamCtx = ctx
+	l.streamsStarted = time.Now()
+
+	// Copied for the stream goroutines, the UI changes them while they run
+	previous, options := l.previous, l.options
+
+	if l.selector != nil {
+		go l.followSelector(ctx, generation, *l.selector, previous, options)
+		return
+	}
 
-			l.textView.Write([]byte(line))
+	go l.refreshPodStatus(ctx, l.pod.Namespace, l.pod.Name)
 
+	containers := l.pod.GetContainers()
+	if l.container != "" || len(containers) == 0 {
+		go l.streamLogs(ctx, generation, logTarget{Namespace: l.pod.Namespace, Pod: l.pod.Name, Container: l.container}, "", "", previous, options)
+		return
+	}
+
+	// Interleave all containers with a colored prefix
+	for i, container := range containers {
+		color := logPrefixColors[i%len(logPrefixColors)]
+		go l.streamLogs(ctx, generation, logTarget{Namespace: l.pod.Namespace, Pod: l.pod.Name, Container: container.Name}, container.Name, color, previous, options)
+	}
+}
+
+// appendLine adds a line to the buffer and scrolls to it if auto-scroll is enabled
+func (l *LogView) appendLine(generation int, line logLine) {
+	if generation != l.buffer.Generation() {
+		return
+	}
+	l.record(line)
+	if !l.buffer.Append(generation, line, l.textView) {
+		return
+	}
+
+	if l.app != nil {
+		l.app.QueueUpdateDraw(func() {
 			// Auto-scroll to bottom if enabled
-			if l.autoScroll && l.app != nil {
-				l.app.QueueUpdateDraw(func() {
-					l.textView.ScrollToEnd()
-				})
+			if l.autoScroll {
+				l.textView.ScrollToEnd()
+			}
+			if l.searchQuery != "" {
+				l.updateHelpBar()
 			}
+		})
+	}
+}
+
+// showInput replaces the help bar with an input field for a search or grep query
+func (l *LogView) showInput(mode string) {
+	l.inputMode = mode
+	if mode == "search" {
+		l.inputPrevious = l.searchQuery
+		l.input.SetLabel("Search: ")
+	} else {
+		l.inputPrevious = l.grepQuery
+		l.input.SetLabel("Grep: ")
+	}
+	l.input.SetText(l.inputPrevious)
+	l.flex.RemoveItem(l.helpBar)
+	l.flex.AddItem(l.input, 1, 0, true)
+	if l.app != nil {
+		l.app.SetFocus(l.input)
+	}
+}
+
+// hideInput restores the help bar after input is finished
+func (l *LogView) hideInput() {
+	l.flex.RemoveItem(l.input)
+	l.flex.AddItem(l.helpBar, 1, 0, false)
+	l.updateHelpBar()
+	if l.app != nil {
+		l.app.SetFocus(l.textView)
+	}
+}
+
+// applyQuery sets the search or grep query and redraws the buffered lines
+func (l *LogView) applyQuery(mode, query string) {
+	re, err := compileLogQuery(query)
+	if err != nil {
+		// Keep the last valid query while the expression is incomplete
+		l.input.SetFieldTextColor(tcell.ColorRed)
+		return
+	}
+	l.input.SetFieldTextColor(tcell.ColorWhite)
+
+	if mode == "search" {
+		l.searchQuery = query
+		l.buffer.SetSearch(re)
+	} else {
+		l.grepQuery = query
+		l.buffer.SetFilter(re)
+	}
+	l.currentMatch = -1
+	l.buffer.Render(l.textView)
+	if l.autoScroll {
+		l.textView.ScrollToEnd()
+	}
+	l.updateHelpBar()
+}
+
+// NextMatch highlights the next or previous search match and scrolls to it
+func (l *LogView) NextMatch(forward bool) {
+	matches := l.buffer.Matches()
+	if l.searchQuery == "" || matches == 0 {
+		return
+	}
+	if l.currentMatch < 0 {
+		// Start from the most recent match, like searching backwards from the end
+		l.currentMatch = matches - 1
+	} else if forward {
+		l.currentMatch = (l.currentMatch + 1) % matches
+	} else {
+		l.currentMatch = (l.currentMatch - 1 + matches) % matches
+	}
+	l.autoScroll = false
+	l.textView.Highlight(fmt.Sprintf("m%d", l.currentMatch)).ScrollToHighlight()
+	l.updateHelpBar()
+}
+
+// updateHelpBar shows the key hints along with the active search and filter
+func (l *LogView) updateHelpBar() {
+	text := " [yellow]Esc[gray] back  [yellow]↑/↓ PgUp/PgDn[gray] scroll  [yellow]Space[gray] auto-scroll  [yellow]c[gray] containers  [yellow]p[gray] previous/current  [yellow]/[gray] search  [yellow]n/N[gray] next/prev  [yellow]g[gray] grep  [yellow]s/z[gray] save/save gzip  [yellow]R[gray] record  [yellow]t/u[gray] timestamps/UTC  [yellow]w[gray] since  [yellow]+/-[gray] tail  [yellow]o[gray] older  [yellow]j[gray] raw/pretty  [yellow]L[gray] min level"
+	text = fmt.Sprintf(" [white]%s[gray] ", l.options) + text
+	if l.grepQuery != "" {
+		text = fmt.Sprintf(" [green]grep: %s[gray] ", tview.Escape(l.grepQuery)) + text
+	}
+	if l.searchQuery != "" {
+		position := "-"
+		if l.currentMatch >= 0 {
+			position = fmt.Sprintf("%d", l.currentMatch+1)
 		}
+		text = fmt.Sprintf(" [yellow]search: %s (%s/%d)[gray] ", tview.Escape(l.searchQuery), position, l.buffer.Matches()) + text
+	}
+	if l.status != "" {
+		text = " " + l.status + "[gray] " + text
 	}
+	l.helpBar.SetText(text)
+}
+
+// setStatus shows the result of an action in the help bar
+func (l *LogView) setStatus(status string) {
+	l.status = status
+	l.updateHelpBar()
 }
 
 // Stop stops the log streaming
 func (l *LogView) Stop() {
-	if l.stopChan != nil {
-		close(l.stopChan)
+	if l.cancel != nil {
+		l.cancel()
+		l.cancel = nil
 	}
+
+	l.stateMu.Lock()
+	l.streamStates = make(map[logTarget]logStreamInfo)
+	l.stateMu.Unlock()
 }
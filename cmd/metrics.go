@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// refreshDurationBuckets are the upper bounds of the refresh latency histogram in seconds
+var refreshDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Metrics collects the dashboard state and refresh statistics and exports
+// them in the Prometheus text exposition format
+type Metrics struct {
+	mu           sync.Mutex
+	cluster      string
+	nodeData     map[string]NodeData
+	changes      map[[2]string]uint64 // Change events by resource and change type
+	refreshes    []uint64             // Refresh count per bucket of refreshDurationBuckets, cumulative when written
+	refreshSum   float64
+	refreshCount uint64 // Total number of refreshes
+	apiErrors    uint64
+	lastRefresh  time.Time // Last successful refresh
+}
+
+// NewMetrics creates an empty metrics collector for a cluster
+func NewMetrics(cluster string) *Metrics {
+	return &Metrics{
+		cluster:   cluster,
+		nodeData:  make(map[string]NodeData),
+		changes:   make(map[[2]string]uint64),
+		refreshes: make([]uint64, len(refreshDurationBuckets)),
+	}
+}
+
+// SetState replaces the node and pod state exported as gauges
+func (m *Metrics) SetState(nodeData map[string]NodeData) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nodeData = nodeData
+	m.lastRefresh = time.Now()
+}
+
+// ObserveRefresh records the duration of a refresh, counting an API error if it failed
+func (m *Metrics) ObserveRefresh(duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seconds := duration.Seconds()
+	for i, bound := range refreshDurationBuckets {
+		if seconds <= bound {
+			m.refreshes[i]++
+			break
+		}
+	}
+	m.refreshSum += seconds
+	m.refreshCount++
+	if err != nil {
+		m.apiErrors++
+	}
+}
+
+// CountAPIError counts a failed API request outside a refresh
+func (m *Metrics) CountAPIError() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apiErrors++
+}
+
+// CountChanges counts change events by resource and change type
+func (m *Metrics) CountChanges(changes []ChangeEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, change := range changes {
+		m.changes[[2]string{change.ResourceType, change.ChangeType}]++
+	}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := &metricsWriter{w: bufio.NewWriter(w)}
+
+	e.family("kubism_cluster_info", "gauge", "Cluster the dashboard is connected to.")
+	e.sample("kubism_cluster_info", 1, "cluster", m.cluster)
+
+	nodeNames := make([]string, 0, len(m.nodeData))
+	for name := range m.nodeData {
+		nodeNames = append(nodeNames, name)
+	}
+	sort.Strings(nodeNames)
+
+	// Node counts by status and version
+	nodeCounts := make(map[[2]string]int)
+	for _, node := range m.nodeData {
+		nodeCounts[[2]string{node.Status, node.Version}]++
+	}
+	e.family("kubism_nodes", "gauge", "Number of nodes by status and kubelet version.")
+	for _, key := range sortedPairs(nodeCounts) {
+		e.sample("kubism_nodes", float64(nodeCounts[key]), "status", key[0], "version", key[1])
+	}
+
+	e.family("kubism_node_status", "gauge", "Status of each node, 1 for the current status.")
+	for _, name := range nodeNames {
+		node := m.nodeData[name]
+		e.sample("kubism_node_status", 1, "node", name, "status", node.Status, "version", node.Version)
+	}
+
+	e.family("kubism_node_condition", "gauge", "Watched node conditions, 1 for the current condition status.")
+	for _, name := range nodeNames {
+		node := m.nodeData[name]
+		for _, condition := range WatchedNodeConditions {
+			if status, ok := node.Conditions[string(condition)]; ok {
+				e.sample("kubism_node_condition", 1, "node", name, "condition", string(condition), "status", status)
+			}
+		}
+	}
+
+	e.family("kubism_node_unschedulable", "gauge", "Whether a node is cordoned.")
+	for _, name := range nodeNames {
+		e.sample("kubism_node_unschedulable", boolValue(m.nodeData[name].Unschedulable), "node", name)
+	}
+
+	// Pods by node, namespace and status, and container restarts. The status
+	// is the one the dashboard shows, e.g. CrashLoopBackOff, not the pod phase.
+	e.family("kubism_pods", "gauge", "Number of pods by node, namespace and status as shown by the dashboard.")
+	for _, name := range nodeNames {
+		podCounts := make(map[[2]string]int)
+		for _, pod := range m.nodeData[name].Pods {
+			podCounts[[2]string{pod.Namespace, pod.Status}]++
+		}
+		for _, key := range sortedPairs(podCounts) {
+			e.sample("kubism_pods", float64(podCounts[key]), "node", name, "namespace", key[0], "status", key[1])
+		}
+	}
+
+	// The kubelet counts restarts per pod, so the counter starts over when a pod
+	// is replaced, usually under a new pod label, and resets for a StatefulSet
+	// pod recreated with the same name, which rate() and increase() handle.
+	e.family("kubism_container_restarts_total", "counter", "Restarts of each container of a pod instance as reported by the kubelet.")
+	for _, name := range nodeNames {
+		pods := m.nodeData[name].Pods
+		podNames := make([]string, 0, len(pods))
+		for podName := range pods {
+			podNames = append(podNames, podName)
+		}
+		sort.Strings(podNames)
+		for _, podName := range podNames {
+			pod := pods[podName]
+			containerNames := make([]string, 0, len(pod.ContainerInfo))
+			for containerName := range pod.ContainerInfo {
+				containerNames = append(containerNames, containerName)
+			}
+			sort.Strings(containerNames)
+			for _, containerName := range containerNames {
+				e.sample("kubism_container_restarts_total", float64(pod.ContainerInfo[containerName].RestartCount),
+					"node", name, "namespace", pod.Namespace, "pod", podName, "container", containerName)
+			}
+		}
+	}
+
+	// Change detection and refresh statistics
+	e.family("kubism_change_events_total", "counter", "Detected change events by resource type and change type.")
+	for _, key := range sortedPairs(m.changes) {
+		e.sample("kubism_change_events_total", float64(m.changes[key]), "resource_type", key[0], "change_type", key[1])
+	}
+
+	e.family("kubism_refresh_duration_seconds", "histogram", "Time taken to fetch nodes and pods from the API server.")
+	var cumulative uint64
+	for i, bound := range refreshDurationBuckets {
+		cumulative += m.refreshes[i]
+		e.sample("kubism_refresh_duration_seconds_bucket", float64(cumulative), "le", strconv.FormatFloat(bound, 'g', -1, 64))
+	}
+	e.sample("kubism_refresh_duration_seconds_bucket", float64(m.refreshCount), "le", "+Inf")
+	e.sample("kubism_refresh_duration_seconds_sum", m.refreshSum)
+	e.sample("kubism_refresh_duration_seconds_count", float64(m.refreshCount))
+
+	e.family("kubism_api_errors_total", "counter", "Failed requests to the API server.")
+	e.sample("kubism_api_errors_total", float64(m.apiErrors))
+
+	e.family("kubism_last_refresh_timestamp_seconds", "gauge", "Unix time of the last successful refresh.")
+	if !m.lastRefresh.IsZero() {
+		e.sample("kubism_last_refresh_timestamp_seconds", float64(m.lastRefresh.UnixNano())/1e9)
+	}
+
+	return e.n, e.flush()
+}
+
+// metricsWriter writes metric families and samples, keeping the first error
+type metricsWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+// family writes the HELP and TYPE lines of a metric
+func (e *metricsWriter) family(name, metricType, help string) {
+	e.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// sample writes one sample with alternating label names and values
+func (e *metricsWriter) sample(name string, value float64, labels ...string) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "%s=\"%s\"", labels[i], escapeLabelValue(labels[i+1]))
+		}
+		b.WriteByte('}')
+	}
+	e.printf("%s %s\n", b.String(), strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func (e *metricsWriter) printf(format string, args ...interface{}) {
+	if e.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(e.w, format, args...)
+	e.n += int64(n)
+	e.err = err
+}
+
+func (e *metricsWriter) flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+// escapeLabelValue escapes backslashes, quotes and newlines in a label value
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// sortedPairs returns the keys of a map keyed by string pairs in order
+func sortedPairs[V any](m map[[2]string]V) [][2]string {
+	keys := make([][2]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	return keys
+}
+
+// boolValue returns 1 for true and 0 for false
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -19,6 +19,9 @@ type MockK8sDataProvider struct {
 	nodeCounter int        // Counter for generating new node names
 	rawData     map[string]RawNodeData
 	podsByNode  map[string]map[string][]string
+	workloads   map[string]WorkloadInfo // By namespace/Kind/name
+	events      map[string]EventInfo    // Warning events by namespace/name
+	eventCount  int                     // Counter for generating event names
 }
 
 // NewMockK8sDataProvider creates a new MockK8sDataProvider
@@ -33,6 +36,21 @@ func NewMockK8sDataProvider() *MockK8sDataProvider {
 		nodeCounter: 3, // Start with 3 initial nodes
 		rawData:     make(map[string]RawNodeData),
 		podsByNode:  make(map[string]map[string][]string),
+		workloads: map[string]WorkloadInfo{
+			workloadKey("default", WorkloadKindDeployment, "web"): {
+				Kind: WorkloadKindDeployment, Namespace: "default",
+				Replicas: 3, ReadyReplicas: 3, UpdatedReplicas: 3, Images: []string{"nginx:1.25"},
+			},
+			workloadKey("monitoring", WorkloadKindStatefulSet, "prometheus"): {
+				Kind: WorkloadKindStatefulSet, Namespace: "monitoring",
+				Replicas: 2, ReadyReplicas: 2, UpdatedReplicas: 2, Images: []string{"prom/prometheus:v2.47.0"},
+			},
+			workloadKey("kube-system", WorkloadKindDaemonSet, "kube-proxy"): {
+				Kind: WorkloadKindDaemonSet, Namespace: "kube-system",
+				Replicas: 3, ReadyReplicas: 3, UpdatedReplicas: 3, Images: []string{"registry.k8s.io/kube-proxy:v1.24.0"},
+			},
+		},
+		events: make(map[string]EventInfo),
 	}
 
 	// Initialize with some default nodes
@@ -196,7 +214,7 @@ func (p *MockK8sDataProvider) UpdateNodeData(includeNamespaces, excludeNamespace
 	}
 
 	// Randomly pick a change type
-	changeType := r.Intn(7)
+	changeType := r.Intn(8)
 
 	// Process changes based on type
 	switch changeType {
@@ -320,6 +338,36 @@ func (p *MockK8sDataProvider) UpdateNodeData(includeNamespaces, excludeNamespace
 				}
 			}
 		}
+
+	case 7: // Change node pressure conditions, cordon or labels
+		if len(nodeNames) > 0 {
+			node := p.nodeMap[nodeNames[r.Intn(len(nodeNames))]]
+			switch r.Intn(3) {
+			case 0:
+				// Toggle one of the pressure conditions following Ready
+				condition := &node.Status.Conditions[1+r.Intn(len(node.Status.Conditions)-1)]
+				if condition.Status == corev1.ConditionFalse {
+					condition.Status = corev1.ConditionTrue
+				} else {
+					condition.Status = corev1.ConditionFalse
+				}
+			case 1:
+				// Cordon or uncordon, tainting the node like kubectl cordon does
+				node.Spec.Unschedulable = !node.Spec.Unschedulable
+				node.Spec.Taints = nil
+				if node.Spec.Unschedulable {
+					node.Spec.Taints = []corev1.Taint{{
+						Key:    corev1.TaintNodeUnschedulable,
+						Effect: corev1.TaintEffectNoSchedule,
+					}}
+				}
+			case 2:
+				if node.Labels == nil {
+					node.Labels = make(map[string]string)
+				}
+				node.Labels["mock.example.com/zone"] = []string{"zone-a", "zone-b", "zone-c"}[r.Intn(3)]
+			}
+		}
 	}
 
 	// Build pods list from pod states and update raw data
@@ -366,3 +414,67 @@ func (p *MockK8sDataProvider) UpdateNodeData(includeNamespaces, excludeNamespace
 
 	return p.filterAndTransformData(p.rawData, criteria)
 }
+
+// UpdateResourceData implements K8sProvider interface, randomly scaling
+// workloads, rolling out images and adding, repeating or expiring events
+func (p *MockK8sDataProvider) UpdateResourceData(includeNamespaces, excludeNamespaces map[string]bool) (map[string]WorkloadInfo, map[string]EventInfo, error) {
+	r := p.rand
+	workloadKeys := sortedKeys(p.workloads, nil)
+	workloadName := workloadKeys[r.Intn(len(workloadKeys))]
+	workload := p.workloads[workloadName]
+
+	switch r.Intn(6) {
+	case 0: // A replica becomes unready or ready again
+		if workload.ReadyReplicas == workload.Replicas {
+			workload.ReadyReplicas--
+		} else {
+			workload.ReadyReplicas = workload.Replicas
+		}
+	case 1: // Scale a Deployment or StatefulSet
+		if workload.Kind != WorkloadKindDaemonSet {
+			workload.Replicas = int32(r.Intn(4) + 1)
+			workload.ReadyReplicas = workload.Replicas
+			workload.UpdatedReplicas = workload.Replicas
+		}
+	case 2: // Add a warning event
+		p.eventCount++
+		reason := []string{"BackOff", "Unhealthy", "FailedScheduling"}[r.Intn(3)]
+		p.events[eventKey(workload.Namespace, fmt.Sprintf("mock-event-%d", p.eventCount))] = EventInfo{
+			Namespace: workload.Namespace,
+			Type:      corev1.EventTypeWarning,
+			Reason:    reason,
+			Object:    "Pod/" + strings.ToLower(workload.Kind) + "-pod",
+			Message:   fmt.Sprintf("mock %s event", reason),
+			Count:     1,
+		}
+	case 3: // Repeat an event
+		if names := sortedKeys(p.events, nil); len(names) > 0 {
+			name := names[r.Intn(len(names))]
+			event := p.events[name]
+			event.Count++
+			p.events[name] = event
+		}
+	case 4: // Expire events, keeping two
+		for _, name := range sortedKeys(p.events, nil) {
+			if len(p.events) <= 2 {
+				break
+			}
+			delete(p.events, name)
+		}
+	}
+	p.workloads[workloadName] = workload
+
+	workloads := make(map[string]WorkloadInfo)
+	for name, workload := range p.workloads {
+		if namespaceShown(workload.Namespace, includeNamespaces, excludeNamespaces) {
+			workloads[name] = workload
+		}
+	}
+	events := make(map[string]EventInfo)
+	for name, event := range p.events {
+		if namespaceShown(event.Namespace, includeNamespaces, excludeNamespaces) {
+			events[name] = event
+		}
+	}
+	return workloads, events, nil
+}
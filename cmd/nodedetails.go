@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
@@ -8,11 +10,16 @@ import (
 	corev1 "k8s.io/api/core/v1"
 )
 
+// nodeDetailsTitle is the title of the node details box
+const nodeDetailsTitle = "Node Details (Use mouse wheel or arrow keys to scroll)"
+
 // NodeDetailsView represents the node details view
 type NodeDetailsView struct {
 	table *tview.Table
 	box   *tview.Box
 	flex  *tview.Flex
+	node  *corev1.Node // Node currently being displayed, nil for a removed node
+	name  string       // Name of the node being displayed, also when removed
 }
 
 // NewNodeDetailsView creates a new NodeDetailsView instance
@@ -24,7 +31,7 @@ func NewNodeDetailsView() *NodeDetailsView {
 	detailsBox := tview.NewBox().
 		SetBorder(true).
 		SetBorderColor(tcell.ColorGray).
-		SetTitle("Node Details (Use mouse wheel or arrow keys to scroll)").
+		SetTitle(nodeDetailsTitle).
 		SetBorderAttributes(tcell.AttrDim)
 
 	// Create a flex container for details
@@ -60,8 +67,22 @@ func (dv *NodeDetailsView) GetFlex() *tview.Flex {
 	return dv.flex
 }
 
+// GetNode returns the node currently being displayed
+func (dv *NodeDetailsView) GetNode() *corev1.Node {
+	return dv.node
+}
+
+// GetNodeName returns the name of the node being displayed, also after it was removed
+func (dv *NodeDetailsView) GetNodeName() string {
+	return dv.name
+}
+
 // ShowNodeDetails displays the details for a given node
 func (dv *NodeDetailsView) ShowNodeDetails(node *corev1.Node) {
+	dv.node = node
+	dv.name = node.Name
+	dv.box.SetTitle(nodeDetailsTitle)
+
 	// Clear and setup details table
 	dv.table.Clear()
 
@@ -134,3 +155,65 @@ func (dv *NodeDetailsView) ShowNodeDetails(node *corev1.Node) {
 		return x, y, width, height
 	})
 }
+
+// UpdateNode shows the latest state of the displayed node in place, keeping
+// the selection and scroll position
+func (dv *NodeDetailsView) UpdateNode(node *corev1.Node) {
+	row, _ := dv.table.GetSelection()
+	offset, _ := dv.table.GetOffset()
+	dv.ShowNodeDetails(node)
+	dv.table.Select(min(row, dv.table.GetRowCount()-1), 0)
+	dv.table.SetOffset(offset, 0)
+}
+
+// ShowCachedNode displays the last known state of a node from the state cache,
+// used when the node is no longer part of the cluster. The name is kept so
+// that UpdateNode resumes if the node comes back.
+func (dv *NodeDetailsView) ShowCachedNode(name string, data NodeData, removedAt time.Time) {
+	dv.node = nil
+	dv.name = name
+	dv.box.SetTitle(fmt.Sprintf("Node Details - last known state of %s, removed at %s", name, removedAt.Format("2006-01-02 15:04:05")))
+	dv.table.Clear()
+
+	row := 0
+	dv.table.SetCell(row, 0, tview.NewTableCell("Last Known State").SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold))
+	row++
+	for _, field := range [][2]string{
+		{"Name", name},
+		{"Status", data.Status},
+		{"Version", data.Version},
+		{"Age", data.Age},
+		{"Pods", data.PodCount},
+		{"Removed", removedAt.Format(time.RFC3339)},
+	} {
+		dv.table.SetCell(row, 0, tview.NewTableCell(field[0]).SetTextColor(tcell.ColorSkyblue))
+		dv.table.SetCell(row, 1, tview.NewTableCell(field[1]).SetTextColor(tcell.ColorWhite))
+		row++
+	}
+
+	// Pods that were running on the node
+	row++
+	dv.table.SetCell(row, 0, tview.NewTableCell("Pods").SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold))
+	row++
+	podNames := make([]string, 0, len(data.Pods))
+	for podName := range data.Pods {
+		podNames = append(podNames, podName)
+	}
+	sort.Strings(podNames)
+	for _, podName := range podNames {
+		pod := data.Pods[podName]
+		dv.table.SetCell(row, 0, tview.NewTableCell(fmt.Sprintf("%s/%s", pod.Namespace, podName)).SetTextColor(tcell.ColorSkyblue))
+		dv.table.SetCell(row, 1, tview.NewTableCell(fmt.Sprintf("%s, %d restarts", pod.Status, pod.RestartCount)).SetTextColor(tcell.ColorWhite))
+		row++
+	}
+
+	// Set initial selection for scrolling
+	dv.table.Select(0, 0)
+
+	// Update details box
+	dv.box.SetDrawFunc(func(screen tcell.Screen, x, y, width, height int) (int, int, int, int) {
+		dv.table.SetRect(x+1, y+1, width-2, height-2)
+		dv.table.Draw(screen)
+		return x, y, width, height
+	})
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
@@ -16,10 +17,62 @@ type NodeData struct {
 	Status        string
 	Version       string
 	Age           string
+	Created       time.Time // Creation time, for sorting by age
 	PodCount      string
 	PodIndicators string
 	Pods          map[string]PodInfo
 	TotalPods     int
+
+	Conditions    map[string]string // Status of the watched node conditions by type, see WatchedNodeConditions
+	Taints        map[string]string // Taints as "key=value:effect" by "key:effect"
+	Unschedulable bool              // Cordoned
+	Labels        map[string]string
+	Annotations   map[string]string
+	Allocatable   map[string]string // Allocatable quantities by resource name
+}
+
+// WatchedNodeConditions are the node conditions, besides Ready, whose status changes are tracked
+var WatchedNodeConditions = []corev1.NodeConditionType{
+	corev1.NodeMemoryPressure,
+	corev1.NodeDiskPressure,
+	corev1.NodePIDPressure,
+	corev1.NodeNetworkUnavailable,
+}
+
+// setNodeProperties copies the tracked conditions, taints, labels, annotations
+// and allocatable resources of a node
+func (d *NodeData) setNodeProperties(node *corev1.Node) {
+	d.Conditions = make(map[string]string)
+	for _, condition := range node.Status.Conditions {
+		for _, watched := range WatchedNodeConditions {
+			if condition.Type == watched {
+				d.Conditions[string(condition.Type)] = string(condition.Status)
+			}
+		}
+	}
+
+	d.Taints = make(map[string]string, len(node.Spec.Taints))
+	for _, taint := range node.Spec.Taints {
+		d.Taints[fmt.Sprintf("%s:%s", taint.Key, taint.Effect)] = taint.ToString()
+	}
+	d.Unschedulable = node.Spec.Unschedulable
+
+	d.Labels = copyStringMap(node.Labels)
+	d.Annotations = copyStringMap(node.Annotations)
+
+	d.Allocatable = make(map[string]string, len(node.Status.Allocatable))
+	for name, quantity := range node.Status.Allocatable {
+		d.Allocatable[string(name)] = quantity.String()
+	}
+}
+
+// copyStringMap returns a copy of m, never nil
+func copyStringMap(m map[string]string) map[string]string {
+	copied := make(map[string]string, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
 }
 
 // CompareNodeData compares two NodeData instances for equality
@@ -135,6 +188,11 @@ func (nv *NodeView) GetNodeMap() map[string]*corev1.Node {
 	return nv.nodeMap
 }
 
+// SetNodeMap replaces the node map. Call it on the UI goroutine, which reads the map.
+func (nv *NodeView) SetNodeMap(nodeMap map[string]*corev1.Node) {
+	nv.nodeMap = nodeMap
+}
+
 // GetVisibleNamespaces returns the map of visible namespaces
 func (nv *NodeView) GetVisibleNamespaces() map[string]bool {
 	return nv.includeNamespaces
@@ -210,8 +268,13 @@ func FormatMapAsRows(table *tview.Table, startRow int, title string, m map[strin
 		return startRow + 1
 	}
 
-	for k, v := range m {
-		table.SetCell(startRow, 0, tview.NewTableCell(fmt.Sprintf(DoubleSpace+"%s: %s", k, v)))
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		table.SetCell(startRow, 0, tview.NewTableCell(fmt.Sprintf(DoubleSpace+"%s: %s", k, m[k])))
 		startRow++
 	}
 
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Fixed columns of the node table, namespace columns follow
+const (
+	NodeColumnName = iota
+	NodeColumnStatus
+	NodeColumnVersion
+	NodeColumnAge
+	NodeColumnPods
+	NodeColumnNamespaces // First namespace column
+)
+
+// Sort indicators appended to the header of the sorted column
+const (
+	sortIndicator         = " ▼"
+	sortIndicatorReversed = " ▲"
+)
+
+// nodeSort is the order of the node table. Each column first sorts the rows
+// needing attention to the top: NotReady nodes, the oldest versions, the
+// oldest nodes, the most pods and the most red pods of a namespace. Ties are
+// ordered by node name.
+type nodeSort struct {
+	column    int    // Fixed column, or NodeColumnNamespaces for the namespace column
+	namespace string // Namespace of a namespace column
+	reversed  bool
+}
+
+// toggle returns the sort by a column, reversing the order if it is already sorted by it
+func (s nodeSort) toggle(column int, namespace string) nodeSort {
+	if column >= NodeColumnNamespaces {
+		column = NodeColumnNamespaces
+	} else {
+		namespace = ""
+	}
+	if s.column == column && s.namespace == namespace {
+		s.reversed = !s.reversed
+		return s
+	}
+	return nodeSort{column: column, namespace: namespace}
+}
+
+// indicator returns the indicator shown in the header of a column
+func (s nodeSort) indicator(column int, namespace string) string {
+	if column >= NodeColumnNamespaces {
+		column = NodeColumnNamespaces
+	} else {
+		namespace = ""
+	}
+	if s.column != column || s.namespace != namespace {
+		return ""
+	}
+	if s.reversed {
+		return sortIndicatorReversed
+	}
+	return sortIndicator
+}
+
+// sortNodeNames orders node names by the sort column
+func (s nodeSort) sortNodeNames(names []string, nodeData map[string]NodeData, podData map[string]map[string][]string) {
+	sort.SliceStable(names, func(i, j int) bool {
+		a, b := names[i], names[j]
+		order := s.compare(nodeData[a], nodeData[b], podData[a][s.namespace], podData[b][s.namespace])
+		if s.reversed {
+			order = -order
+		}
+		if order == 0 {
+			return a < b
+		}
+		return order < 0
+	})
+}
+
+// compare returns a negative number if node a sorts before b, given the pod
+// indicators of both in the sorted namespace
+func (s nodeSort) compare(a, b NodeData, aIndicators, bIndicators []string) int {
+	switch s.column {
+	case NodeColumnStatus:
+		return compareInts(statusRank(a.Status), statusRank(b.Status))
+	case NodeColumnVersion:
+		return compareVersions(a.Version, b.Version)
+	case NodeColumnAge:
+		return a.Created.Compare(b.Created)
+	case NodeColumnPods:
+		return compareInts(len(b.Pods), len(a.Pods))
+	case NodeColumnNamespaces:
+		aRed, aYellow := countIndicators(aIndicators)
+		bRed, bYellow := countIndicators(bIndicators)
+		if order := compareInts(bRed, aRed); order != 0 {
+			return order
+		}
+		if order := compareInts(bYellow, aYellow); order != 0 {
+			return order
+		}
+		return compareInts(len(bIndicators), len(aIndicators))
+	}
+	return strings.Compare(a.Name, b.Name)
+}
+
+// statusRank orders NotReady and unknown statuses before Ready
+func statusRank(status string) int {
+	if status == NodeStatusReady {
+		return 1
+	}
+	return 0
+}
+
+// countIndicators returns the number of red and yellow pod indicators
+func countIndicators(indicators []string) (red, yellow int) {
+	for _, indicator := range indicators {
+		switch {
+		case strings.Contains(indicator, ColorTagRed):
+			red++
+		case strings.Contains(indicator, ColorTagYellow):
+			yellow++
+		}
+	}
+	return red, yellow
+}
+
+// compareVersions compares kubelet versions such as v1.27.3-eks-a5565ad by
+// their numeric parts, so that v1.9 sorts before v1.27
+func compareVersions(a, b string) int {
+	aParts, bParts := versionParts(a), versionParts(b)
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if order := compareInts(aParts[i], bParts[i]); order != 0 {
+			return order
+		}
+	}
+	if order := compareInts(len(aParts), len(bParts)); order != 0 {
+		return order
+	}
+	return strings.Compare(a, b)
+}
+
+// versionParts returns the numbers of the major.minor.patch part of a version
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// compareInts returns -1, 0 or 1 as a is less than, equal to or greater than b
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
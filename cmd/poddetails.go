@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
@@ -9,10 +10,13 @@ import (
 
 // PodDetailsView represents the pod details view
 type PodDetailsView struct {
-	table *tview.Table
-	box   *tview.Box
-	flex  *tview.Flex
-	pods  map[string]PodInfo // Store pods map for reference
+	table     *tview.Table
+	box       *tview.Box
+	flex      *tview.Flex
+	pods      map[string]PodInfo // Store pods map for reference
+	nodeName  string
+	namespace string
+	lastKnown map[string]PodInfo // Removed pods shown from their last known state
 }
 
 // NewPodDetailsView creates a new PodDetailsView instance
@@ -67,11 +71,71 @@ func (dv *PodDetailsView) GetPodInfo(podName string) (PodInfo, bool) {
 	return pod, ok
 }
 
+// GetLocation returns the node and namespace of the pods being displayed
+func (dv *PodDetailsView) GetLocation() (string, string) {
+	return dv.nodeName, dv.namespace
+}
+
 // ShowPodDetails displays the details for pods on a given node and namespace
 func (dv *PodDetailsView) ShowPodDetails(nodeName string, namespace string, pods map[string]PodInfo) {
 	// Store pods map for reference
 	dv.pods = pods
+	dv.nodeName = nodeName
+	dv.namespace = namespace
+	dv.lastKnown = nil
 
+	// Update title with node and namespace info
+	dv.box.SetTitle(fmt.Sprintf("Pod Details - Node: %s, Namespace: %s (Use mouse wheel or arrow keys to scroll)", nodeName, namespace))
+
+	dv.renderPods()
+
+	// Set initial selection for scrolling
+	dv.table.Select(1, 0)
+
+	// Update details box
+	dv.box.SetDrawFunc(func(screen tcell.Screen, x, y, width, height int) (int, int, int, int) {
+		dv.table.SetRect(x+1, y+1, width-2, height-2)
+		dv.table.Draw(screen)
+		return x, y, width, height
+	})
+}
+
+// UpdatePods replaces the displayed pods with their latest state in place,
+// keeping the selected pod and scroll position. Pods shown from their last
+// known state stay listed.
+func (dv *PodDetailsView) UpdatePods(pods map[string]PodInfo) {
+	for podName, pod := range dv.lastKnown {
+		if _, exists := pods[podName]; !exists {
+			pods[podName] = pod
+		}
+	}
+	dv.pods = pods
+
+	row, _ := dv.table.GetSelection()
+	offset, _ := dv.table.GetOffset()
+	selected := ""
+	if row > 0 && row < dv.table.GetRowCount() {
+		selected = dv.table.GetCell(row, 0).Text
+	}
+
+	dv.renderPods()
+
+	if !dv.SelectPod(selected) {
+		dv.table.Select(min(max(row, 1), dv.table.GetRowCount()-1), 0)
+	}
+	dv.table.SetOffset(offset, 0)
+}
+
+// SetLastKnown lists a removed pod with its last known state, also after updates
+func (dv *PodDetailsView) SetLastKnown(podName string, pod PodInfo) {
+	if dv.lastKnown == nil {
+		dv.lastKnown = make(map[string]PodInfo)
+	}
+	dv.lastKnown[podName] = pod
+}
+
+// renderPods fills the table with the stored pods sorted by name
+func (dv *PodDetailsView) renderPods() {
 	// Clear and setup details table
 	dv.table.Clear()
 
@@ -86,12 +150,17 @@ func (dv *PodDetailsView) ShowPodDetails(nodeName string, namespace string, pods
 		dv.table.SetCell(0, i, cell)
 	}
 
-	// Update title with node and namespace info
-	dv.box.SetTitle(fmt.Sprintf("Pod Details - Node: %s, Namespace: %s (Use mouse wheel or arrow keys to scroll)", nodeName, namespace))
-
 	// Add pod rows
+	podNames := make([]string, 0, len(dv.pods))
+	for podName := range dv.pods {
+		podNames = append(podNames, podName)
+	}
+	sort.Strings(podNames)
+
 	row := 1
-	for podName, podInfo := range pods {
+	for _, podName := range podNames {
+		podInfo := dv.pods[podName]
+
 		// Pod Name
 		dv.table.SetCell(row, 0, tview.NewTableCell(podName).
 			SetTextColor(tcell.ColorSkyblue))
@@ -127,23 +196,34 @@ func (dv *PodDetailsView) ShowPodDetails(nodeName string, namespace string, pods
 			SetTextColor(restartColor))
 
 		// Container Status
+		containerNames := make([]string, 0, len(podInfo.ContainerInfo))
+		for containerName := range podInfo.ContainerInfo {
+			containerNames = append(containerNames, containerName)
+		}
+		sort.Strings(containerNames)
 		var containerStatus string
-		for containerName, container := range podInfo.ContainerInfo {
-			containerStatus += fmt.Sprintf("%s: %s\n", containerName, container.Status)
+		for _, containerName := range containerNames {
+			containerStatus += fmt.Sprintf("%s: %s\n", containerName, podInfo.ContainerInfo[containerName].Status)
 		}
 		dv.table.SetCell(row, 4, tview.NewTableCell(containerStatus).
 			SetTextColor(tcell.ColorWhite))
 
 		row++
 	}
+}
 
-	// Set initial selection for scrolling
-	dv.table.Select(1, 0)
+// SelectPod selects the row of the given pod, reporting whether it is listed
+func (dv *PodDetailsView) SelectPod(podName string) bool {
+	for row := 1; row < dv.table.GetRowCount(); row++ {
+		if dv.table.GetCell(row, 0).Text == podName {
+			dv.table.Select(row, 0)
+			return true
+		}
+	}
+	return false
+}
 
-	// Update details box
-	dv.box.SetDrawFunc(func(screen tcell.Screen, x, y, width, height int) (int, int, int, int) {
-		dv.table.SetRect(x+1, y+1, width-2, height-2)
-		dv.table.Draw(screen)
-		return x, y, width, height
-	})
+// SetNote appends a note to the title, e.g. to mark pods shown from their last known state
+func (dv *PodDetailsView) SetNote(note string) {
+	dv.box.SetTitle(fmt.Sprintf("Pod Details - Node: %s, Namespace: %s - %s", dv.nodeName, dv.namespace, note))
 }
@@ -1,72 +1,162 @@
 package cmd
 
 import (
+	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 )
 
 // PodInfo represents information about a pod and its containers
 type PodInfo struct {
-	Name          string
-	Namespace     string
-	Status        string
-	RestartCount  int
-	ContainerInfo map[string]ContainerInfo
+	Name                   string
+	Namespace              string
+	Status                 string
+	RestartCount           int
+	ContainerInfo          map[string]ContainerInfo
+	InitContainerInfo      map[string]ContainerInfo
+	EphemeralContainerInfo map[string]ContainerInfo
 }
 
 // ContainerInfo represents information about a container
 type ContainerInfo struct {
-	Status       string
-	RestartCount int
+	Status          string
+	RestartCount    int
+	LastTermination *TerminationInfo // Last terminated instance, nil if it never terminated
+}
+
+// TerminationInfo describes how a container instance terminated
+type TerminationInfo struct {
+	Reason     string
+	ExitCode   int32
+	FinishedAt time.Time
+}
+
+// String returns a short description of the termination
+func (t TerminationInfo) String() string {
+	reason := t.Reason
+	if reason == "" {
+		reason = "Terminated"
+	}
+	return fmt.Sprintf("%s, exit code %d", reason, t.ExitCode)
 }
 
 // GetPodInfo extracts PodInfo from a Kubernetes Pod
 func GetPodInfo(pod *corev1.Pod) PodInfo {
 	podInfo := PodInfo{
-		Name:          pod.Name,
-		Namespace:     pod.Namespace,
-		Status:        string(pod.Status.Phase),
-		RestartCount:  0,
-		ContainerInfo: make(map[string]ContainerInfo),
+		Name:                   pod.Name,
+		Namespace:              pod.Namespace,
+		Status:                 string(pod.Status.Phase),
+		RestartCount:           0,
+		ContainerInfo:          make(map[string]ContainerInfo),
+		InitContainerInfo:      make(map[string]ContainerInfo),
+		EphemeralContainerInfo: make(map[string]ContainerInfo),
 	}
 
 	// Get container information
 	for _, container := range pod.Spec.Containers {
-		var containerStatus *corev1.ContainerStatus
-		for i := range pod.Status.ContainerStatuses {
-			if pod.Status.ContainerStatuses[i].Name == container.Name {
-				containerStatus = &pod.Status.ContainerStatuses[i]
-				break
-			}
+		info := getContainerInfo(container.Name, pod.Status.ContainerStatuses)
+		podInfo.RestartCount += info.RestartCount
+		podInfo.ContainerInfo[container.Name] = info
+	}
+
+	// Init and ephemeral containers are tracked separately so they don't
+	// affect readiness and restart totals
+	for _, container := range pod.Spec.InitContainers {
+		podInfo.InitContainerInfo[container.Name] = getContainerInfo(container.Name, pod.Status.InitContainerStatuses)
+	}
+	for _, container := range pod.Spec.EphemeralContainers {
+		podInfo.EphemeralContainerInfo[container.Name] = getContainerInfo(container.Name, pod.Status.EphemeralContainerStatuses)
+	}
+
+	// Handle terminating state
+	if pod.DeletionTimestamp != nil {
+		podInfo.Status = PodStatusTerminating
+	}
+
+	return podInfo
+}
+
+// getContainerInfo builds the ContainerInfo for a container from the matching status
+func getContainerInfo(name string, statuses []corev1.ContainerStatus) ContainerInfo {
+	var containerStatus *corev1.ContainerStatus
+	for i := range statuses {
+		if statuses[i].Name == name {
+			containerStatus = &statuses[i]
+			break
 		}
+	}
 
-		status := PodStatusUnknown
-		restartCount := 0
-		if containerStatus != nil {
-			if containerStatus.State.Running != nil {
-				status = PodStatusRunning
-			} else if containerStatus.State.Waiting != nil {
-				status = containerStatus.State.Waiting.Reason
-			} else if containerStatus.State.Terminated != nil {
-				status = containerStatus.State.Terminated.Reason
+	info := ContainerInfo{Status: PodStatusUnknown}
+	if containerStatus != nil {
+		if containerStatus.State.Running != nil {
+			info.Status = PodStatusRunning
+		} else if containerStatus.State.Waiting != nil {
+			info.Status = containerStatus.State.Waiting.Reason
+		} else if containerStatus.State.Terminated != nil {
+			info.Status = containerStatus.State.Terminated.Reason
+		}
+		info.RestartCount = int(containerStatus.RestartCount)
+		if terminated := containerStatus.LastTerminationState.Terminated; terminated != nil {
+			info.LastTermination = &TerminationInfo{
+				Reason:     terminated.Reason,
+				ExitCode:   terminated.ExitCode,
+				FinishedAt: terminated.FinishedAt.Time,
 			}
-			restartCount = int(containerStatus.RestartCount)
-			podInfo.RestartCount += restartCount
 		}
+	}
 
-		podInfo.ContainerInfo[container.Name] = ContainerInfo{
-			Status:       status,
-			RestartCount: restartCount,
+	return info
+}
+
+// ContainerRef identifies a container of a pod along with its kind
+type ContainerRef struct {
+	Name string
+	Kind string // "container", "init" or "ephemeral"
+}
+
+// GetContainerInfo returns the info for a container of any kind
+func (p PodInfo) GetContainerInfo(name string) (ContainerInfo, bool) {
+	for _, containers := range []map[string]ContainerInfo{p.ContainerInfo, p.InitContainerInfo, p.EphemeralContainerInfo} {
+		if info, ok := containers[name]; ok {
+			return info, true
 		}
 	}
+	return ContainerInfo{}, false
+}
 
-	// Handle terminating state
-	if pod.DeletionTimestamp != nil {
-		podInfo.Status = PodStatusTerminating
+// Label returns the display label for the container
+func (c ContainerRef) Label() string {
+	if c.Kind == "container" {
+		return c.Name
 	}
+	return c.Kind + ": " + c.Name
+}
 
-	return podInfo
+// GetContainers returns all containers of the pod, regular containers first
+// followed by init and ephemeral containers, each group sorted by name
+func (p PodInfo) GetContainers() []ContainerRef {
+	var refs []ContainerRef
+	for _, group := range []struct {
+		kind       string
+		containers map[string]ContainerInfo
+	}{
+		{"container", p.ContainerInfo},
+		{"init", p.InitContainerInfo},
+		{"ephemeral", p.EphemeralContainerInfo},
+	} {
+		names := make([]string, 0, len(group.containers))
+		for name := range group.containers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			refs = append(refs, ContainerRef{Name: name, Kind: group.kind})
+		}
+	}
+	return refs
 }
 
 // GetPodIndicator returns a visual indicator for pod status
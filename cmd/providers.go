@@ -42,6 +42,14 @@ type K8sProvider interface {
 	// - error: any error that occurred
 	GetFilteredData(criteria FilterCriteria) (map[string]NodeData, map[string]map[string][]string, error)
 
+	// UpdateResourceData fetches the Deployments, StatefulSets and DaemonSets
+	// and the warning events of the namespaces passing the filters
+	// Returns:
+	// - map[string]WorkloadInfo: workloads by namespace/Kind/name
+	// - map[string]EventInfo: events by namespace/name
+	// - error: any error that occurred
+	UpdateResourceData(includeNamespaces, excludeNamespaces map[string]bool) (map[string]WorkloadInfo, map[string]EventInfo, error)
+
 	// GetPodsByNode returns the current pod data by node
 	GetPodsByNode() map[string]map[string][]string
 }
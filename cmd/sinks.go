@@ -0,0 +1,463 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Payload kinds
+const (
+	SinkPayloadChange = "change"
+	SinkPayloadAlert  = "alert"
+)
+
+// What sinks deliver, see Config.SinkDeliver
+const (
+	SinkDeliverAll     = "all"
+	SinkDeliverChanges = "changes"
+	SinkDeliverAlerts  = "alerts"
+)
+
+const (
+	// SinkQueueSize is the number of payloads queued per sink, newer ones are
+	// dropped while the queue is full
+	SinkQueueSize = 1000
+
+	// SinkTimeout limits a single delivery attempt
+	SinkTimeout = 10 * time.Second
+
+	// SinkCloseTimeout is how long queued payloads are delivered for at exit
+	SinkCloseTimeout = 5 * time.Second
+
+	// SlackWebhookTemplate is the name of the built-in Slack-compatible webhook template
+	SlackWebhookTemplate = "slack"
+)
+
+// slackTemplateText posts the summary of a payload as a Slack message
+const slackTemplateText = `{"text": {{ json .Text }}}`
+
+// SinkRetry controls how often and how fast failed deliveries are retried
+type SinkRetry struct {
+	Attempts       int           // Including the first attempt
+	InitialBackoff time.Duration // Doubled after every failed attempt
+	MaxBackoff     time.Duration
+}
+
+// DefaultSinkRetry is the retry policy of the sinks created from flags
+var DefaultSinkRetry = SinkRetry{Attempts: 5, InitialBackoff: time.Second, MaxBackoff: 30 * time.Second}
+
+// AlertRecord is the JSON form of an alert delivered to sinks
+type AlertRecord struct {
+	ID           int       `json:"id"`
+	Rule         string    `json:"rule"`
+	Severity     string    `json:"severity"`
+	ResourceType string    `json:"resourceType"`
+	ResourceName string    `json:"resourceName,omitempty"`
+	Namespace    string    `json:"namespace,omitempty"`
+	Message      string    `json:"message"`
+	FirstSeen    time.Time `json:"firstSeen"`
+	LastSeen     time.Time `json:"lastSeen"`
+	Count        int       `json:"count"`
+}
+
+// SinkPayload is delivered to sinks for every change event or alert
+type SinkPayload struct {
+	Kind    string        `json:"kind"` // change or alert
+	Cluster string        `json:"cluster"`
+	Time    time.Time     `json:"time"`
+	Text    string        `json:"text"` // One-line summary, e.g. for chat messages
+	Change  *ChangeRecord `json:"change,omitempty"`
+	Alert   *AlertRecord  `json:"alert,omitempty"`
+}
+
+// NewChangePayload creates the payload of a change event
+func NewChangePayload(change ChangeEvent, cluster string) SinkPayload {
+	record := NewChangeRecord(cluster, change)
+	return SinkPayload{
+		Kind:    SinkPayloadChange,
+		Cluster: cluster,
+		Time:    change.Timestamp,
+		Text:    record.text(),
+		Change:  &record,
+	}
+}
+
+// NewAlertPayload creates the payload of a fired alert
+func NewAlertPayload(alert Alert, cluster string) SinkPayload {
+	return SinkPayload{
+		Kind:    SinkPayloadAlert,
+		Cluster: cluster,
+		Time:    alert.LastSeen,
+		Text:    fmt.Sprintf("[%s] %s alert %s: %s", cluster, strings.ToUpper(alert.Severity), alert.Rule, alert.Message),
+		Alert: &AlertRecord{
+			ID:           alert.ID,
+			Rule:         alert.Rule,
+			Severity:     alert.Severity,
+			ResourceType: alert.ResourceType,
+			ResourceName: alert.ResourceName,
+			Namespace:    alert.Namespace,
+			Message:      alert.Message,
+			FirstSeen:    alert.FirstSeen,
+			LastSeen:     alert.LastSeen,
+			Count:        alert.Count,
+		},
+	}
+}
+
+// Sink delivers payloads to an external system
+type Sink interface {
+	// Name identifies the sink in error messages
+	Name() string
+
+	// Send delivers one payload. Errors wrapped with permanentError are not retried.
+	Send(ctx context.Context, payload SinkPayload) error
+}
+
+// permanentError marks a delivery error that retrying won't fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// LoadWebhookTemplate parses the webhook body template from a file, or the
+// built-in Slack template for "slack". The template is executed with a
+// SinkPayload and must produce JSON; the json function quotes a value.
+func LoadWebhookTemplate(name string) (*template.Template, error) {
+	text := slackTemplateText
+	if name != SlackWebhookTemplate {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+	return template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(text)
+}
+
+// WebhookSink posts payloads as JSON to an HTTP endpoint
+type WebhookSink struct {
+	URL      string
+	Template *template.Template // Renders the request body, nil posts the payload as is
+	Client   *http.Client
+}
+
+// Name implements Sink
+func (s *WebhookSink) Name() string {
+	return "webhook " + s.URL
+}
+
+// Send implements Sink. Client errors other than 408 and 429 are not retried.
+func (s *WebhookSink) Send(ctx context.Context, payload SinkPayload) error {
+	var body bytes.Buffer
+	if s.Template != nil {
+		if err := s.Template.Execute(&body, payload); err != nil {
+			return permanentError{fmt.Errorf("template: %v", err)}
+		}
+		if !json.Valid(body.Bytes()) {
+			return permanentError{fmt.Errorf("template produced invalid JSON: %s", body.String())}
+		}
+	} else if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		return permanentError{err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, &body)
+	if err != nil {
+		return permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return permanentError{err}
+	}
+	return err
+}
+
+// SplitCommand splits a command line into the program and its arguments like
+// a POSIX shell: words are separated by whitespace, single quotes keep text
+// as is, and a backslash escapes the next character outside quotes and
+// before ", $, ` and \ inside double quotes. Variables and globs are not
+// expanded.
+func SplitCommand(command string) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(command[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(command) && command[i] != '"'; i++ {
+				if command[i] == '\\' && i+1 < len(command) && strings.IndexByte("\"$`\\", command[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(command[i])
+			}
+			if i >= len(command) {
+				return nil, errors.New("unterminated double quote")
+			}
+			inWord = true
+		case c == '\\':
+			if i+1 >= len(command) {
+				return nil, errors.New("trailing backslash")
+			}
+			i++
+			word.WriteByte(command[i])
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	}
+	return args, nil
+}
+
+// ExecSink runs a command for every payload with the payload JSON on stdin
+type ExecSink struct {
+	Command []string // Program and arguments
+}
+
+// Name implements Sink
+func (s *ExecSink) Name() string {
+	return "exec " + strings.Join(s.Command, " ")
+}
+
+// Send implements Sink, failing if the command exits with a non-zero status
+func (s *ExecSink) Send(ctx context.Context, payload SinkPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return permanentError{err}
+	}
+	cmd := exec.CommandContext(ctx, s.Command[0], s.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(append(data, '\n'))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if len(output) > 0 {
+			return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+		}
+		return err
+	}
+	return nil
+}
+
+// FileSink appends payloads to a file as JSON Lines
+type FileSink struct {
+	Path string
+}
+
+// Name implements Sink
+func (s *FileSink) Name() string {
+	return "file " + s.Path
+}
+
+// Send implements Sink. The file is opened for every payload so it can be
+// moved away by external log rotation.
+func (s *FileSink) Send(ctx context.Context, payload SinkPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return permanentError{err}
+	}
+	file, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// SinkDispatcher queues payloads for each sink and delivers them in the
+// background, retrying failed deliveries with exponential backoff
+type SinkDispatcher struct {
+	workers   []*sinkWorker
+	retry     SinkRetry
+	errorFunc func(sink Sink, err error)
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	mu        sync.Mutex // Guards closed against concurrent Deliver and Close
+	closed    bool
+}
+
+// sinkWorker delivers the queued payloads of one sink in order
+type sinkWorker struct {
+	sink  Sink
+	queue chan SinkPayload
+}
+
+// NewSinkDispatcher starts delivering to the given sinks
+func NewSinkDispatcher(sinks []Sink, retry SinkRetry) *SinkDispatcher {
+	if retry.Attempts < 1 {
+		retry.Attempts = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &SinkDispatcher{retry: retry, ctx: ctx, cancel: cancel}
+	for _, sink := range sinks {
+		worker := &sinkWorker{sink: sink, queue: make(chan SinkPayload, SinkQueueSize)}
+		d.workers = append(d.workers, worker)
+		d.wg.Add(1)
+		go d.run(worker)
+	}
+	return d
+}
+
+// SetErrorFunc sets the handler called when a payload can't be delivered or
+// is dropped. It's called from the delivery goroutines.
+func (d *SinkDispatcher) SetErrorFunc(handler func(sink Sink, err error)) {
+	d.errorFunc = handler
+}
+
+// Deliver queues a payload for every sink without blocking. Payloads
+// delivered after Close are dropped.
+func (d *SinkDispatcher) Deliver(payload SinkPayload) {
+	var dropped []Sink
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	for _, worker := range d.workers {
+		select {
+		case worker.queue <- payload:
+		default:
+			dropped = append(dropped, worker.sink)
+		}
+	}
+	d.mu.Unlock()
+
+	// Report outside the lock, the error handler may block
+	for _, sink := range dropped {
+		d.reportError(sink, fmt.Errorf("queue full, dropped %s payload", payload.Kind))
+	}
+}
+
+// Close stops accepting payloads and waits up to timeout for the queued ones
+// to be delivered before abandoning them
+func (d *SinkDispatcher) Close(timeout time.Duration) {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	for _, worker := range d.workers {
+		close(worker.queue)
+	}
+	d.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+	d.cancel()
+}
+
+// run delivers the payloads of one sink until its queue is closed
+func (d *SinkDispatcher) run(worker *sinkWorker) {
+	defer d.wg.Done()
+	for payload := range worker.queue {
+		if err := d.send(worker.sink, payload); err != nil {
+			d.reportError(worker.sink, err)
+		}
+	}
+}
+
+// send delivers a payload, retrying with backoff
+func (d *SinkDispatcher) send(sink Sink, payload SinkPayload) error {
+	backoff := d.retry.InitialBackoff
+	var err error
+	for attempt := 1; attempt <= d.retry.Attempts; attempt++ {
+		ctx, cancel := context.WithTimeout(d.ctx, SinkTimeout)
+		err = sink.Send(ctx, payload)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		var permanent permanentError
+		if errors.As(err, &permanent) || attempt == d.retry.Attempts {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-d.ctx.Done():
+			return err
+		}
+		backoff *= 2
+		if d.retry.MaxBackoff > 0 && backoff > d.retry.MaxBackoff {
+			backoff = d.retry.MaxBackoff
+		}
+	}
+	return err
+}
+
+// reportError passes a delivery error to the error handler
+func (d *SinkDispatcher) reportError(sink Sink, err error) {
+	if d.errorFunc != nil {
+		d.errorFunc(sink, err)
+	}
+}
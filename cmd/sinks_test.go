@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testSinkRetry retries quickly so the tests don't wait for real backoffs
+var testSinkRetry = SinkRetry{Attempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+// testPayload is delivered by the sink tests
+var testPayload = SinkPayload{Kind: SinkPayloadChange, Cluster: "test", Text: "Node node-1 Modified Status: Ready → NotReady"}
+
+// deliver sends one payload to a sink through a dispatcher and returns the
+// delivery errors
+func deliver(t *testing.T, sink Sink) []error {
+	t.Helper()
+	var mu sync.Mutex
+	var errs []error
+	dispatcher := NewSinkDispatcher([]Sink{sink}, testSinkRetry)
+	dispatcher.SetErrorFunc(func(sink Sink, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	})
+	dispatcher.Deliver(testPayload)
+	dispatcher.Close(5 * time.Second)
+	mu.Lock()
+	defer mu.Unlock()
+	return errs
+}
+
+func TestWebhookSinkRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int // Response status of each request, the last one repeats
+		wantRequests int32
+		wantErr      bool
+	}{
+		{name: "success", statuses: []int{http.StatusOK}, wantRequests: 1},
+		{name: "server error retried", statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusNoContent}, wantRequests: 3},
+		{name: "server error gives up", statuses: []int{http.StatusInternalServerError}, wantRequests: 3, wantErr: true},
+		{name: "rate limit retried", statuses: []int{http.StatusTooManyRequests, http.StatusOK}, wantRequests: 2},
+		{name: "client error not retried", statuses: []int{http.StatusBadRequest}, wantRequests: 1, wantErr: true},
+		{name: "not found not retried", statuses: []int{http.StatusNotFound}, wantRequests: 1, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(requests.Add(1))
+				w.WriteHeader(test.statuses[min(n, len(test.statuses))-1])
+			}))
+			defer server.Close()
+
+			errs := deliver(t, &WebhookSink{URL: server.URL, Client: server.Client()})
+			if got := requests.Load(); got != test.wantRequests {
+				t.Errorf("got %d requests, want %d", got, test.wantRequests)
+			}
+			if (len(errs) > 0) != test.wantErr {
+				t.Errorf("got errors %v, want error %v", errs, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookSinkBody(t *testing.T) {
+	invalid := filepath.Join(t.TempDir(), "invalid.tmpl")
+	if err := os.WriteFile(invalid, []byte(`{"text": {{ .Text }}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		template string // Empty posts the payload as is
+		want     map[string]interface{}
+		wantErr  bool
+	}{
+		{
+			name: "payload",
+			want: map[string]interface{}{"kind": "change", "cluster": "test", "text": testPayload.Text, "time": "0001-01-01T00:00:00Z"},
+		},
+		{
+			name:     "slack",
+			template: SlackWebhookTemplate,
+			want:     map[string]interface{}{"text": testPayload.Text},
+		},
+		{
+			name:     "invalid JSON",
+			template: invalid,
+			wantErr:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var bodies []map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
+					t.Errorf("got Content-Type %q", contentType)
+				}
+				var body map[string]interface{}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("decoding body: %v", err)
+				}
+				bodies = append(bodies, body)
+			}))
+			defer server.Close()
+
+			sink := &WebhookSink{URL: server.URL, Client: server.Client()}
+			if test.template != "" {
+				tmpl, err := LoadWebhookTemplate(test.template)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sink.Template = tmpl
+			}
+			errs := deliver(t, sink)
+			if test.wantErr {
+				// Template errors are permanent, nothing is posted
+				if len(errs) != 1 || len(bodies) != 0 {
+					t.Errorf("got errors %v and bodies %v, want one error and no request", errs, bodies)
+				}
+				return
+			}
+			if len(errs) > 0 {
+				t.Fatalf("got errors %v", errs)
+			}
+			if want := []map[string]interface{}{test.want}; !reflect.DeepEqual(bodies, want) {
+				t.Errorf("got bodies %v, want %v", bodies, want)
+			}
+		})
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{command: "notify-send-wrapper --urgent", want: []string{"notify-send-wrapper", "--urgent"}},
+		{command: "  spaced \t out  ", want: []string{"spaced", "out"}},
+		{command: `notify --title 'Cluster change' --body "it's down"`, want: []string{"notify", "--title", "Cluster change", "--body", "it's down"}},
+		{command: `echo "a \"quoted\" \$word" '\n'`, want: []string{"echo", `a "quoted" $word`, `\n`}},
+		{command: `path\ with\ spaces x""y ''`, want: []string{"path with spaces", "xy", ""}},
+		{command: "", wantErr: true},
+		{command: "echo 'unterminated", wantErr: true},
+		{command: `echo "unterminated`, wantErr: true},
+		{command: `echo \`, wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := SplitCommand(test.command)
+		if (err != nil) != test.wantErr || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SplitCommand(%q) = %q, %v, want %q, error %v", test.command, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestExecSink(t *testing.T) {
+	dir := t.TempDir()
+	argsFile, stdinFile := filepath.Join(dir, "args"), filepath.Join(dir, "stdin")
+	script := `printf '%s\n' "$@" > "$0"; cat > ` + stdinFile
+
+	errs := deliver(t, &ExecSink{Command: []string{"sh", "-c", script, argsFile, "two words", "$HOME"}})
+	if len(errs) > 0 {
+		t.Fatalf("got errors %v", errs)
+	}
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(args), "two words\n$HOME\n"; got != want {
+		t.Errorf("got arguments %q, want %q", got, want)
+	}
+	stdin, err := os.ReadFile(stdinFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload SinkPayload
+	if err := json.Unmarshal(stdin, &payload); err != nil || payload.Text != testPayload.Text {
+		t.Errorf("got stdin %q (%v), want the payload JSON", stdin, err)
+	}
+}
+
+func TestExecSinkFailure(t *testing.T) {
+	var attempts atomic.Int32
+	errs := deliver(t, &countingSink{Sink: &ExecSink{Command: []string{"sh", "-c", "echo broken >&2; exit 3"}}, attempts: &attempts})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken") {
+		t.Errorf("got errors %v, want one with the command output", errs)
+	}
+	if got := attempts.Load(); got != int32(testSinkRetry.Attempts) {
+		t.Errorf("got %d attempts, want %d", got, testSinkRetry.Attempts)
+	}
+}
+
+func TestSinkDispatcherClosed(t *testing.T) {
+	var attempts atomic.Int32
+	sink := &countingSink{Sink: &FileSink{Path: filepath.Join(t.TempDir(), "sink.jsonl")}, attempts: &attempts}
+	dispatcher := NewSinkDispatcher([]Sink{sink}, testSinkRetry)
+	dispatcher.Close(time.Second)
+	dispatcher.Close(time.Second)
+	dispatcher.Deliver(testPayload)
+	if got := attempts.Load(); got != 0 {
+		t.Errorf("got %d deliveries after Close, want none", got)
+	}
+}
+
+// countingSink counts the delivery attempts of a sink
+type countingSink struct {
+	Sink
+	attempts *atomic.Int32
+}
+
+func (s *countingSink) Send(ctx context.Context, payload SinkPayload) error {
+	s.attempts.Add(1)
+	return s.Sink.Send(ctx, payload)
+}
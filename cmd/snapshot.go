@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Snapshot output formats
+const (
+	SnapshotFormatTable    = "table"
+	SnapshotFormatJSON     = "json"
+	SnapshotFormatCSV      = "csv"
+	SnapshotFormatMarkdown = "markdown"
+)
+
+// ASCII glyphs replacing the colored pod indicators outside the TUI. None of
+// them starts a spreadsheet formula, so CSV cells stay plain text.
+const (
+	SnapshotGlyphRed    = "x" // Failed or unknown
+	SnapshotGlyphYellow = "!" // Pending or restarted
+	SnapshotGlyphGreen  = "o" // Running
+)
+
+// snapshotLegend explains the pod glyphs below tables
+const snapshotLegend = "Pods: " + SnapshotGlyphRed + " failed, " + SnapshotGlyphYellow + " pending or restarted, " + SnapshotGlyphGreen + " running"
+
+// Snapshot is the node and pod state of a cluster at one point in time
+type Snapshot struct {
+	Cluster    string                       `json:"cluster"`
+	Time       time.Time                    `json:"time"`
+	Namespaces []string                     `json:"namespaces"`
+	Nodes      []NodeData                   `json:"nodes"`     // Sorted by name, with glyphs as PodIndicators
+	PodGlyphs  map[string]map[string]string `json:"podGlyphs"` // Pod glyphs by node and namespace
+}
+
+// TakeSnapshot fetches the current state once, with the namespace filters
+// and search query of the config applied
+func TakeSnapshot(config *Config) (*Snapshot, error) {
+	provider, err := newProvider(config)
+	if err != nil {
+		return nil, err
+	}
+	nodeData, podsByNode, err := fetchFilteredData(provider, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load data: %v", err)
+	}
+	return NewSnapshot(provider.GetClusterName(), nodeData, podsByNode), nil
+}
+
+// NewSnapshot creates a snapshot of node data and pod indicators as returned by a provider
+func NewSnapshot(cluster string, nodeData map[string]NodeData, podsByNode map[string]map[string][]string) *Snapshot {
+	snapshot := &Snapshot{
+		Cluster:    cluster,
+		Time:       time.Now(),
+		Namespaces: []string{},
+		Nodes:      []NodeData{},
+		PodGlyphs:  make(map[string]map[string]string),
+	}
+	namespaceSet := make(map[string]bool)
+	for _, namespacePods := range podsByNode {
+		for ns := range namespacePods {
+			if !namespaceSet[ns] {
+				namespaceSet[ns] = true
+				snapshot.Namespaces = append(snapshot.Namespaces, ns)
+			}
+		}
+	}
+	sort.Strings(snapshot.Namespaces)
+
+	for _, data := range nodeData {
+		data.PodIndicators = podIndicatorGlyphs(podsByNode[data.Name], snapshot.Namespaces)
+		snapshot.Nodes = append(snapshot.Nodes, data)
+		snapshot.PodGlyphs[data.Name] = make(map[string]string)
+		for ns := range podsByNode[data.Name] {
+			snapshot.PodGlyphs[data.Name][ns] = podIndicatorGlyphs(podsByNode[data.Name], []string{ns})
+		}
+	}
+	sort.Slice(snapshot.Nodes, func(i, j int) bool {
+		return snapshot.Nodes[i].Name < snapshot.Nodes[j].Name
+	})
+	return snapshot
+}
+
+// Write writes the snapshot in the given format, table if empty
+func (s *Snapshot) Write(w io.Writer, format string) error {
+	switch format {
+	case "", SnapshotFormatTable:
+		return s.writeTable(w)
+	case SnapshotFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(s)
+	case SnapshotFormatCSV:
+		return s.writeCSV(w)
+	case SnapshotFormatMarkdown:
+		return s.writeMarkdown(w)
+	}
+	return fmt.Errorf("unsupported snapshot format %q", format)
+}
+
+// rows returns the node×namespace matrix with a header row, like the main table
+func (s *Snapshot) rows() [][]string {
+	rows := [][]string{append([]string{"Node Name", "Status", "Version", "Age", "PODS"}, s.Namespaces...)}
+	for _, node := range s.Nodes {
+		row := []string{node.Name, node.Status, node.Version, node.Age, node.PodCount}
+		for _, ns := range s.Namespaces {
+			row = append(row, s.PodGlyphs[node.Name][ns])
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// header describes the cluster and time of the snapshot
+func (s *Snapshot) header() string {
+	return fmt.Sprintf("Cluster %s at %s", s.Cluster, s.Time.Format("2006-01-02 15:04:05 MST"))
+}
+
+// writeTable writes an aligned plain text table
+func (s *Snapshot) writeTable(w io.Writer) error {
+	fmt.Fprintf(w, "%s\n\n", s.header())
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, row := range s.rows() {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%s\n", snapshotLegend)
+	return err
+}
+
+// writeCSV writes the matrix as CSV with a header row
+func (s *Snapshot) writeCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(s.rows()); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// writeMarkdown writes a Markdown table for pasting into tickets
+func (s *Snapshot) writeMarkdown(w io.Writer) error {
+	fmt.Fprintf(w, "**%s**\n\n", markdownEscape(s.header()))
+	rows := s.rows()
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			switch {
+			case i > 0 && j >= 5 && cell != "":
+				cells[j] = "`" + cell + "`" // Keep the glyphs monospaced
+			default:
+				cells[j] = markdownEscape(cell)
+			}
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+		if i == 0 {
+			fmt.Fprintf(w, "|%s\n", strings.Repeat(" --- |", len(row)))
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%s\n", snapshotLegend)
+	return err
+}
+
+// markdownEscape escapes the characters that break Markdown table cells or add formatting
+func markdownEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`").Replace(text)
+}
+
+// podIndicatorGlyphs returns the glyphs of the pod indicators of the given namespaces
+func podIndicatorGlyphs(indicators map[string][]string, namespaces []string) string {
+	var glyphs strings.Builder
+	for _, ns := range namespaces {
+		for _, indicator := range indicators[ns] {
+			glyphs.WriteString(podIndicatorGlyph(indicator))
+		}
+	}
+	return glyphs.String()
+}
+
+// podIndicatorGlyph returns the ASCII glyph of a colored pod indicator
+func podIndicatorGlyph(indicator string) string {
+	switch {
+	case strings.Contains(indicator, ColorTagRed):
+		return SnapshotGlyphRed
+	case strings.Contains(indicator, ColorTagYellow):
+		return SnapshotGlyphYellow
+	}
+	return SnapshotGlyphGreen
+}
@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
@@ -16,7 +18,9 @@ type UI struct {
 	detailsView    *NodeDetailsView
 	podDetailsView *PodDetailsView
 	logView        *LogView
+	editView       *EditView
 	changeLogView  *ChangeLogView
+	alertsView     *AlertsView // Nil without alert rules
 	mainApp        *App
 	focusIndex     int
 	components     []tview.Primitive
@@ -27,6 +31,7 @@ type UI struct {
 	mainBox        *tview.Box
 	viewStack      []string        // Track view navigation
 	searchBox      *tview.TextView // Display search query
+	nodeSort       nodeSort        // Order of the node table, kept across refreshes
 }
 
 // NewUI creates a new UI instance
@@ -103,19 +108,34 @@ func (ui *UI) Setup() error {
 	ui.logView = NewLogView()
 	ui.logView.SetApplication(ui.app)
 	ui.logView.SetMainApp(ui.mainApp)
+	ui.logView.SetDoneFunc(ui.returnToPreviousView)
+	ui.logView.SetMaxLines(ui.mainApp.config.LogMaxLines)
+	ui.logView.SetExportDir(ui.mainApp.config.LogExportDir)
+	ui.editView = NewEditView()
+	ui.editView.SetApplication(ui.app)
 
 	// Create changelog view
-	ui.changeLogView = NewChangeLogView(ui.mainApp.config.LogFilePath)
+	ui.changeLogView = NewChangeLogView(ui.mainApp.config.ChangeLogFileOptions(), ui.mainApp.GetProvider().GetClusterName(), ui.mainApp.config.ChangeLogSize)
 	changeLogTable := ui.changeLogView.GetTable()
+	if err := ui.changeLogView.SetFilter(ui.mainApp.config.ChangeFilter); err != nil {
+		return fmt.Errorf("invalid change filter: %v", err)
+	}
 
 	// Create search box
 	ui.searchBox = tview.NewTextView().
 		SetDynamicColors(true).
 		SetTextColor(tcell.ColorWhite)
+	ui.updateSearchBox()
 
 	// Track focusable components
 	ui.components = []tview.Primitive{table, changeLogTable}
 
+	// Create the alerts pane when alert rules are configured
+	if engine := ui.mainApp.GetAlertEngine(); engine != nil {
+		ui.alertsView = NewAlertsView(engine)
+		ui.components = append(ui.components, ui.alertsView.GetTable())
+	}
+
 	// Create a box to hold everything
 	ui.mainBox = tview.NewBox().
 		SetBorder(true).
@@ -127,6 +147,12 @@ func (ui *UI) Setup() error {
 	// Set the application and box in the changelog view for flashing effect
 	ui.changeLogView.SetApplication(ui.app)
 	ui.changeLogView.SetBox(ui.mainBox)
+	ui.changeLogView.SetOpenFunc(ui.openChange)
+	ui.changeLogView.SetDoneFunc(func() {
+		if ui.getCurrentView() == "changes" {
+			ui.toggleChangeLogFullScreen()
+		}
+	})
 
 	// Create a flex container for the table and changelog
 	mainFlex := tview.NewFlex().
@@ -134,6 +160,9 @@ func (ui *UI) Setup() error {
 
 	// Add items to mainFlex with proper focus handling
 	mainFlex.AddItem(table, 0, 2, true)
+	if ui.alertsView != nil {
+		mainFlex.AddItem(ui.alertsView.GetTable(), AlertsPaneHeight, 0, false)
+	}
 	mainFlex.AddItem(ui.changeLogView.GetFlex(), 0, 1, false)
 	mainFlex.AddItem(ui.searchBox, 1, 0, false) // Add search box at the bottom
 
@@ -205,6 +234,11 @@ func (ui *UI) setupKeyboardHandling() {
 			return nil
 		}
 
+		// Let the log selector prompt and the changelog filter handle their own input
+		if ui.pages.HasPage("selector") || ui.changeLogView.IsEditingFilter() {
+			return event
+		}
+
 		// If help modal is active, only handle Esc key
 		if ui.pages.HasPage("help") {
 			if event.Key() == tcell.KeyEscape {
@@ -258,6 +292,31 @@ func (ui *UI) setupKeyboardHandling() {
 			}
 		}
 
+		// The log view handles its own keys, including Esc
+		if ui.getCurrentView() == "logs" {
+			return event
+		}
+
+		// The full-screen changelog handles its own keys, including Esc
+		if ui.getCurrentView() == "changes" {
+			if event.Rune() == KeyChangeLogFullScreen {
+				ui.toggleChangeLogFullScreen()
+				return nil
+			}
+			switch event.Rune() {
+			case KeyHelp:
+				ui.ShowHelpModal()
+				return nil
+			case KeyRefresh:
+				ui.mainApp.TriggerRefresh()
+				return nil
+			case KeyClearHistory:
+				ui.changeLogView.Clear()
+				return nil
+			}
+			return event
+		}
+
 		// Handle global '?' key for help when no modal is active
 		if !ui.hasActiveModal() && event.Rune() == KeyHelp {
 			ui.ShowHelpModal()
@@ -275,30 +334,29 @@ func (ui *UI) setupKeyboardHandling() {
 		// Handle ESC key based on current view
 		if event.Key() == tcell.KeyEscape {
 			switch ui.getCurrentView() {
-			case "logs":
-				// Return to pod details view
-				ui.mainApp.SetShowingPods(true)
-				ui.app.SetRoot(ui.podDetailsView.GetFlex(), true)
-				ui.app.SetFocus(ui.podDetailsView.GetTable())
-				ui.popView()
-				return nil
 			case "pods":
-				// Return to main view
+				// Return to the main view or the full-screen changelog
 				ui.mainApp.SetShowingPods(false)
-				ui.app.SetRoot(ui.pages, true)
-				ui.app.SetFocus(ui.nodeView.GetTable())
-				ui.popView()
+				ui.returnToPreviousView()
 				return nil
 			case "details":
-				// Return to main view
+				// Return to the main view or the full-screen changelog
 				ui.mainApp.SetShowingDetails(false)
-				ui.app.SetRoot(ui.pages, true)
-				ui.app.SetFocus(ui.nodeView.GetTable())
-				ui.popView()
+				ui.returnToPreviousView()
+				return nil
+			case "edit":
+				// Return to the view the edit was started from
+				ui.editView.Cancel()
+				ui.returnToPreviousView()
 				return nil
 			}
 		}
 
+		// If showing the edit view, handle its specific keys
+		if ui.getCurrentView() == "edit" {
+			return ui.handleEditViewKeys(event)
+		}
+
 		// If showing pod details, handle its specific keys
 		if ui.mainApp.IsShowingPods() {
 			return ui.handlePodDetailsViewKeys(event)
@@ -318,6 +376,12 @@ func (ui *UI) setupKeyboardHandling() {
 			case KeyRefresh:
 				ui.mainApp.TriggerRefresh()
 				return nil
+			case KeySelectorLogs:
+				ui.showLogSelectorPrompt()
+				return nil
+			case KeyChangeLogFullScreen:
+				ui.toggleChangeLogFullScreen()
+				return nil
 			}
 
 			// Handle Tab key
@@ -327,6 +391,16 @@ func (ui *UI) setupKeyboardHandling() {
 				return nil
 			}
 
+			// Let the focused changelog scroll and filter itself
+			if ui.app.GetFocus() == ui.changeLogView.GetTable() {
+				return event
+			}
+
+			// Let the focused alerts pane handle acknowledge and silence
+			if ui.alertsView != nil && ui.app.GetFocus() == ui.alertsView.GetTable() {
+				return event
+			}
+
 			return ui.handleMainViewKeys(event)
 		}
 
@@ -380,13 +454,26 @@ func (ui *UI) handlePodDetailsViewKeys(event *tcell.EventKey) *tcell.EventKey {
 				ui.logView.SetPreviousApp(ui.podDetailsView.GetFlex())
 				// Store the current table and selection for restoration
 				ui.logView.SetPreviousSelection(ui.podDetailsView.GetTable(), row)
-				ui.logView.ShowPodLogs(ui.mainApp.GetProvider().(*RealK8sDataProvider).client, &podInfo)
+				client, _ := ui.mainApp.GetKubeClient()
+				ui.logView.ShowPodLogs(client, &podInfo)
 				ui.app.SetRoot(ui.logView.GetFlex(), true)
 				// Add logs view to stack
 				ui.pushView("logs")
 			}
 		}
 		return nil
+	case tcell.KeyRune:
+		if event.Rune() == KeyEdit && row > 0 {
+			podName := ui.podDetailsView.GetTable().GetCell(row, 0).Text
+			if podInfo, ok := ui.podDetailsView.GetPodInfo(podName); ok {
+				ui.showEditView("Pod", podInfo.Namespace, podName)
+			}
+			return nil
+		}
+		if event.Rune() == KeyPodsLogs {
+			ui.showPodDetailsLogs()
+			return nil
+		}
 	case tcell.KeyUp:
 		if row > 0 {
 			ui.podDetailsView.GetTable().Select(row-1, 0)
@@ -425,6 +512,13 @@ func (ui *UI) handlePodDetailsViewKeys(event *tcell.EventKey) *tcell.EventKey {
 func (ui *UI) handleDetailsViewKeys(event *tcell.EventKey) *tcell.EventKey {
 	row, _ := ui.detailsView.GetTable().GetSelection()
 	switch event.Key() {
+	case tcell.KeyRune:
+		if event.Rune() == KeyEdit {
+			if node := ui.detailsView.GetNode(); node != nil {
+				ui.showEditView("Node", "", node.Name)
+			}
+			return nil
+		}
 	case tcell.KeyUp:
 		if row > 0 {
 			ui.detailsView.GetTable().Select(row-1, 0)
@@ -459,6 +553,236 @@ func (ui *UI) handleDetailsViewKeys(event *tcell.EventKey) *tcell.EventKey {
 	return event
 }
 
+// showPodDetailsLogs tails the logs of every pod on the node and namespace shown in
+// the pod details view, following pods that are added or removed later
+func (ui *UI) showPodDetailsLogs() {
+	nodeName, namespace := ui.podDetailsView.GetLocation()
+	selector := PodSelector{
+		Description:   fmt.Sprintf("node %s, namespace %s", nodeName, namespace),
+		Namespace:     namespace,
+		FieldSelector: "spec.nodeName=" + nodeName,
+	}
+
+	// Keep the pod name filter that was applied to the pod list
+	searchState := ui.mainApp.GetSearchState()
+	if searchState.Active && searchState.Query != "" {
+		selector.NameRegex = regexp.MustCompile("(?i)" + regexp.QuoteMeta(searchState.Query))
+		selector.Description += fmt.Sprintf(", filter %q", searchState.Query)
+	}
+
+	client, _ := ui.mainApp.GetKubeClient()
+	ui.logView.ShowSelectorLogs(client, selector)
+	ui.app.SetRoot(ui.logView.GetFlex(), true)
+	ui.pushView("logs")
+}
+
+// showLogSelectorPrompt asks for a label selector or pod name regex and tails
+// the logs of all matching pods
+func (ui *UI) showLogSelectorPrompt() {
+	input := tview.NewInputField().
+		SetLabel("Label selector or ~name-regex: ").
+		SetFieldWidth(40).
+		SetFieldBackgroundColor(tcell.ColorBlack)
+	input.SetBorder(true).
+		SetTitle(" Tail Logs ").
+		SetBorderColor(tcell.ColorGray)
+
+	input.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			ui.pages.RemovePage("selector")
+			ui.app.SetFocus(ui.nodeView.GetTable())
+			return
+		}
+		if key != tcell.KeyEnter {
+			return
+		}
+
+		selector, err := ParsePodSelector(input.GetText())
+		if err != nil {
+			input.SetTitle(fmt.Sprintf(" Tail Logs - %s ", tview.Escape(err.Error())))
+			input.SetTitleColor(tcell.ColorRed)
+			return
+		}
+		selector.IncludeNamespaces = ui.mainApp.config.IncludeNamespaces
+		selector.ExcludeNamespaces = ui.mainApp.config.ExcludeNamespaces
+
+		ui.pages.RemovePage("selector")
+		client, _ := ui.mainApp.GetKubeClient()
+		ui.logView.ShowSelectorLogs(client, selector)
+		ui.app.SetRoot(ui.logView.GetFlex(), true)
+		ui.pushView("logs")
+	})
+
+	frame := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(input, 80, 0, true).
+			AddItem(nil, 0, 1, false), 3, 0, true).
+		AddItem(nil, 0, 1, false)
+	ui.pages.AddPage("selector", frame, true, true)
+	ui.app.SetFocus(input)
+}
+
+// toggleChangeLogFullScreen shows the changelog on its own or returns it to the main view
+func (ui *UI) toggleChangeLogFullScreen() {
+	if ui.getCurrentView() == "changes" {
+		ui.popView()
+		ui.app.SetRoot(ui.pages, true)
+		for i, component := range ui.components {
+			if component == ui.changeLogView.GetTable() {
+				ui.focusIndex = i
+			}
+		}
+		ui.app.SetFocus(ui.changeLogView.GetTable())
+		return
+	}
+	ui.app.SetRoot(ui.changeLogView.GetFlex(), true)
+	ui.app.SetFocus(ui.changeLogView.GetTable())
+	ui.pushView("changes")
+}
+
+// showDetailsView switches to the node details view
+func (ui *UI) showDetailsView() {
+	ui.mainApp.SetShowingDetails(true)
+	ui.app.SetRoot(ui.detailsView.GetFlex(), true)
+	ui.app.SetFocus(ui.detailsView.GetTable())
+	ui.pushView("details")
+}
+
+// showPodDetailsView switches to the pod details view
+func (ui *UI) showPodDetailsView() {
+	ui.mainApp.SetShowingPods(true)
+	ui.app.SetRoot(ui.podDetailsView.GetFlex(), true)
+	ui.app.SetFocus(ui.podDetailsView.GetTable())
+	ui.pushView("pods")
+}
+
+// openChange navigates to the resource of a changelog entry: node details for
+// nodes, the pod row in pod details for pods and containers, or the pod logs
+// if logs is set. Workloads and events have no view to open. Resources that no longer exist are shown from their last
+// known state in the state cache.
+func (ui *UI) openChange(change ChangeEvent, logs bool) {
+	parts := strings.Split(change.ResourceName, "/")
+	stateCache := ui.mainApp.GetStateCache()
+
+	if change.ResourceType == "Node" {
+		if logs {
+			ui.changeLogView.SetStatus("[yellow]logs are only available for pods and containers[-]")
+			return
+		}
+		if node, ok := ui.nodeView.GetNodeMap()[change.ResourceName]; ok {
+			ui.detailsView.ShowNodeDetails(node)
+			ui.showDetailsView()
+			return
+		}
+		state, _, found := stateCache.GetLastKnown(change.ResourceName)
+		data, ok := state.Data.(NodeData)
+		if !found || !ok {
+			ui.changeLogView.SetStatus(fmt.Sprintf("[red]no cached state for node %s[-]", tview.Escape(change.ResourceName)))
+			return
+		}
+		ui.detailsView.ShowCachedNode(change.ResourceName, data, state.Timestamp)
+		ui.showDetailsView()
+		return
+	}
+
+	if len(parts) < 2 || (change.ResourceType != "Pod" && change.ResourceType != "Container") {
+		ui.changeLogView.SetStatus(fmt.Sprintf("[red]can't open %s %s[-]", change.ResourceType, tview.Escape(change.ResourceName)))
+		return
+	}
+	nodeName, podName := parts[0], parts[1]
+	container := ""
+	if change.ResourceType == "Container" && len(parts) > 2 {
+		container = parts[2]
+	}
+
+	state, removed, found := stateCache.GetLastKnown(nodeName + "/" + podName)
+	podInfo, ok := state.Data.(PodInfo)
+	if !found || !ok {
+		ui.changeLogView.SetStatus(fmt.Sprintf("[red]no cached state for pod %s[-]", tview.Escape(podName)))
+		return
+	}
+
+	if logs {
+		if removed {
+			ui.changeLogView.SetStatus(fmt.Sprintf("[yellow]pod %s no longer exists[-]", tview.Escape(podName)))
+			return
+		}
+		client, _ := ui.mainApp.GetKubeClient()
+		ui.logView.ShowContainerLogs(client, &podInfo, container)
+		ui.app.SetRoot(ui.logView.GetFlex(), true)
+		ui.pushView("logs")
+		return
+	}
+
+	// List the pods of the namespace on the node, adding the removed pod
+	namespacePods := make(map[string]PodInfo)
+	if node, ok := ui.nodeView.GetLastNodeData()[nodeName]; ok {
+		for name, pod := range node.Pods {
+			if pod.Namespace == podInfo.Namespace {
+				namespacePods[name] = pod
+			}
+		}
+	}
+	namespacePods[podName] = podInfo
+	ui.podDetailsView.ShowPodDetails(nodeName, podInfo.Namespace, namespacePods)
+	ui.podDetailsView.SelectPod(podName)
+	if removed {
+		ui.podDetailsView.SetLastKnown(podName, podInfo)
+		ui.podDetailsView.SetNote(fmt.Sprintf("%s shows its last known state, removed at %s", podName, state.Timestamp.Format("2006-01-02 15:04:05")))
+	} else {
+		ui.podDetailsView.SetNote("press Enter for logs")
+	}
+	ui.showPodDetailsView()
+}
+
+// showEditView opens a resource in the editor and shows the resulting diff
+func (ui *UI) showEditView(kind, namespace, name string) {
+	client, _ := ui.mainApp.GetKubeClient()
+	ui.app.SetRoot(ui.editView.GetFlex(), true)
+	ui.app.SetFocus(ui.editView.GetTextView())
+	ui.pushView("edit")
+	ui.editView.Edit(client, kind, namespace, name)
+}
+
+// returnToPreviousView pops the current view and restores the one it was opened from
+func (ui *UI) returnToPreviousView() {
+	switch ui.popView() {
+	case "pods":
+		ui.app.SetRoot(ui.podDetailsView.GetFlex(), true)
+		ui.app.SetFocus(ui.podDetailsView.GetTable())
+	case "details":
+		ui.app.SetRoot(ui.detailsView.GetFlex(), true)
+		ui.app.SetFocus(ui.detailsView.GetTable())
+	case "changes":
+		ui.app.SetRoot(ui.changeLogView.GetFlex(), true)
+		ui.app.SetFocus(ui.changeLogView.GetTable())
+	default:
+		ui.app.SetRoot(ui.pages, true)
+		ui.app.SetFocus(ui.components[ui.focusIndex])
+	}
+}
+
+// handleEditViewKeys handles keyboard input for the edit view
+func (ui *UI) handleEditViewKeys(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Rune() {
+	case KeyApplyEdit:
+		ui.editView.Apply(func() {
+			if ui.getCurrentView() == "edit" {
+				ui.returnToPreviousView()
+			}
+			ui.mainApp.TriggerRefresh()
+		})
+		return nil
+	case KeyEdit:
+		ui.editView.Reopen()
+		return nil
+	}
+	return event
+}
+
 // handleMainViewKeys handles keyboard input for the main view
 func (ui *UI) handleMainViewKeys(event *tcell.EventKey) *tcell.EventKey {
 	table := ui.nodeView.GetTable()
@@ -484,64 +808,196 @@ func (ui *UI) handleMainViewKeys(event *tcell.EventKey) *tcell.EventKey {
 			table.Select(row, col+1)
 		}
 		return nil
+	case tcell.KeyRune:
+		if event.Rune() == KeySortColumn {
+			ui.nodeSort = ui.nodeSort.toggle(col, columnName(table, col))
+			ui.UpdateTable(ui.nodeView.GetLastNodeData(), ui.nodeView.GetLastPodData())
+			return nil
+		}
 	case tcell.KeyEnter:
 		nodeName := table.GetCell(row, 0).Text
 		if col <= 4 { // Node columns
 			if node, ok := ui.nodeView.GetNodeMap()[nodeName]; ok {
 				ui.detailsView.ShowNodeDetails(node)
-				ui.mainApp.SetShowingDetails(true)
-				ui.app.SetRoot(ui.detailsView.GetFlex(), true)
-				ui.app.SetFocus(ui.detailsView.GetTable())
-				ui.pushView("details")
+				ui.showDetailsView()
 				return nil
 			}
 		} else { // Pod columns
-			namespace := table.GetCell(0, col).Text
-			// Get the current search query
-			searchState := ui.mainApp.GetSearchState()
-			var searchQuery string
-			if searchState.SearchMode {
-				searchQuery = searchState.TempQuery
-			} else if searchState.Active {
-				searchQuery = searchState.Query
+			namespace := columnName(table, col)
+			if namespacePods, ok := ui.namespacePods(nodeName, namespace); ok {
+				ui.podDetailsView.ShowPodDetails(nodeName, namespace, namespacePods)
+				ui.showPodDetailsView()
+				return nil
 			}
+		}
+	}
+	return event
+}
+
+// SetSearch applies a search filter as if it was entered after /, an empty
+// query clears it
+func (ui *UI) SetSearch(query string) {
+	searchState := ui.mainApp.GetSearchState()
+	searchState.SearchMode = false
+	searchState.TempQuery = ""
+	searchState.Active = query != ""
+	searchState.Query = query
+	ui.updateSearchBox()
+	ui.UpdateTable(ui.nodeView.GetLastNodeData(), ui.nodeView.GetLastPodData())
+}
 
-			// Create filter criteria
-			criteria := FilterCriteria{
-				IncludeNamespaces: ui.mainApp.config.IncludeNamespaces,
-				ExcludeNamespaces: ui.mainApp.config.ExcludeNamespaces,
-				SearchQuery:       searchQuery,
+// CurrentView returns the name of the current view and the node of the
+// selected main table row
+func (ui *UI) CurrentView() (view, node string) {
+	table := ui.nodeView.GetTable()
+	if row, _ := table.GetSelection(); row > 0 && row < table.GetRowCount() {
+		node = table.GetCell(row, 0).Text
+	}
+	return ui.getCurrentView(), node
+}
+
+// NavigateTo leaves the open views and selects a node in the main table, or
+// shows the pods of one of its namespaces if namespace is set
+func (ui *UI) NavigateTo(nodeName, namespace string) error {
+	table := ui.nodeView.GetTable()
+	row := -1
+	for r := 1; r < table.GetRowCount(); r++ {
+		if table.GetCell(r, 0).Text == nodeName {
+			row = r
+			break
+		}
+	}
+	if row < 0 {
+		return fmt.Errorf("node %q is not shown", nodeName)
+	}
+	col := 0
+	if namespace != "" {
+		for c := 5; c < table.GetColumnCount(); c++ {
+			if columnName(table, c) == namespace {
+				col = c
+				break
 			}
+		}
+		if col == 0 {
+			return fmt.Errorf("namespace %q is not shown", namespace)
+		}
+	}
 
-			// Get filtered data
-			nodeData, _, err := ui.mainApp.GetProvider().GetFilteredData(criteria)
-			if err == nil {
-				if node, ok := nodeData[nodeName]; ok {
-					// Filter pods by namespace
-					namespacePods := make(map[string]PodInfo)
-					for podName, podInfo := range node.Pods {
-						if podInfo.Namespace == namespace {
-							namespacePods[podName] = podInfo
-						}
-					}
+	ui.closeViews()
+	for i, component := range ui.components {
+		if component == table {
+			ui.focusIndex = i
+		}
+	}
+	ui.app.SetFocus(table)
+	table.Select(row, col)
+
+	if namespace != "" {
+		namespacePods, ok := ui.namespacePods(nodeName, namespace)
+		if !ok {
+			return fmt.Errorf("node %q is not shown", nodeName)
+		}
+		ui.podDetailsView.ShowPodDetails(nodeName, namespace, namespacePods)
+		ui.showPodDetailsView()
+	}
+	return nil
+}
+
+// closeViews closes the prompts and drill-down views, returning to the main view
+func (ui *UI) closeViews() {
+	if ui.pages.HasPage("help") {
+		ui.DismissHelpModal()
+	}
+	ui.pages.RemovePage("selector")
+	for ui.getCurrentView() != "main" {
+		switch ui.getCurrentView() {
+		case "logs":
+			ui.logView.Stop()
+			ui.logView.stopRecording()
+		case "pods":
+			ui.mainApp.SetShowingPods(false)
+		case "details":
+			ui.mainApp.SetShowingDetails(false)
+		}
+		ui.returnToPreviousView()
+	}
+}
+
+// namespacePods returns the pods of a namespace on a node that match the
+// current search, ok is false if the node is not found. It reads the data of
+// the last refresh held by the node view, the provider is only used by the
+// refresh goroutine.
+func (ui *UI) namespacePods(nodeName, namespace string) (map[string]PodInfo, bool) {
+	// Get the current search query
+	searchState := ui.mainApp.GetSearchState()
+	var searchQuery string
+	if searchState.SearchMode {
+		searchQuery = searchState.TempQuery
+	} else if searchState.Active {
+		searchQuery = searchState.Query
+	}
 
-					ui.podDetailsView.ShowPodDetails(nodeName, namespace, namespacePods)
-					ui.mainApp.SetShowingPods(true)
-					ui.app.SetRoot(ui.podDetailsView.GetFlex(), true)
-					ui.app.SetFocus(ui.podDetailsView.GetTable())
-					ui.pushView("pods")
-					return nil
+	node, ok := ui.nodeView.GetLastNodeData()[nodeName]
+	if !ok {
+		return nil, false
+	}
+
+	// Filter pods by namespace and search query, matching pod names like the provider
+	namespacePods := make(map[string]PodInfo)
+	for podName, podInfo := range node.Pods {
+		if podInfo.Namespace != namespace {
+			continue
+		}
+		if searchQuery != "" && !strings.Contains(strings.ToLower(podName), strings.ToLower(searchQuery)) {
+			continue
+		}
+		namespacePods[podName] = podInfo
+	}
+	return namespacePods, true
+}
+
+// refreshDetailViews updates the open node and pod details views in place
+// after a refresh. A node that went away is shown from its last known state.
+func (ui *UI) refreshDetailViews() {
+	if ui.mainApp.IsShowingDetails() {
+		if name := ui.detailsView.GetNodeName(); name != "" {
+			if node, ok := ui.nodeView.GetNodeMap()[name]; ok {
+				ui.detailsView.UpdateNode(node)
+			} else if ui.detailsView.GetNode() != nil {
+				// Switch to the last known state once, keeping the scroll position afterwards
+				if state, _, found := ui.mainApp.GetStateCache().GetLastKnown(name); found {
+					if data, ok := state.Data.(NodeData); ok {
+						ui.detailsView.ShowCachedNode(name, data, state.Timestamp)
+					}
 				}
 			}
 		}
 	}
-	return event
+
+	if ui.mainApp.IsShowingPods() {
+		nodeName, namespace := ui.podDetailsView.GetLocation()
+		namespacePods, ok := ui.namespacePods(nodeName, namespace)
+		if !ok {
+			namespacePods = make(map[string]PodInfo)
+		}
+		ui.podDetailsView.UpdatePods(namespacePods)
+	}
+}
+
+// columnName returns the name of a node table column, without the sort indicator
+func columnName(table *tview.Table, col int) string {
+	name, _ := table.GetCell(0, col).GetReference().(string)
+	return name
 }
 
 // UpdateTable updates the table with fresh node and pod data
 func (ui *UI) UpdateTable(nodeData map[string]NodeData, podsByNode map[string]map[string][]string) {
 	table := ui.nodeView.GetTable()
 	currentRow, currentCol := table.GetSelection()
+	var selectedNode string
+	if currentRow > 0 && currentRow < table.GetRowCount() {
+		selectedNode = table.GetCell(currentRow, 0).Text
+	}
 
 	// Store the complete data
 	ui.nodeView.SetAllData(nodeData, podsByNode)
@@ -578,9 +1034,10 @@ func (ui *UI) UpdateTable(nodeData map[string]NodeData, podsByNode map[string]ma
 
 	headers = append(headers, namespaces...)
 
-	// Set up header row
+	// Set up header row, the column names are kept as references because of the sort indicator
 	for i, header := range headers {
-		cell := tview.NewTableCell(header).
+		cell := tview.NewTableCell(header + ui.nodeSort.indicator(i, header)).
+			SetReference(header).
 			SetTextColor(tcell.ColorWhite).
 			SetSelectable(false).
 			SetExpansion(1).
@@ -594,15 +1051,21 @@ func (ui *UI) UpdateTable(nodeData map[string]NodeData, podsByNode map[string]ma
 		table.SetCell(0, i, cell)
 	}
 
+	// Mark nodes and namespaces with resources flapping in the change log
+	flapping := newFlapSet(ui.changeLogView.Flapping(time.Now()))
+
 	var nodeNames []string
 	for name := range filteredNodeData {
 		nodeNames = append(nodeNames, name)
 	}
-	sort.Strings(nodeNames)
+	ui.nodeSort.sortNodeNames(nodeNames, filteredNodeData, filteredPodData)
 
 	i := 1
 	for _, nodeName := range nodeNames {
 		data := filteredNodeData[nodeName]
+		if nodeName == selectedNode {
+			currentRow = i // Follow the selected node when the order changes
+		}
 
 		// Node Name column
 		table.SetCell(i, 0, tview.NewTableCell(data.Name).
@@ -610,7 +1073,11 @@ func (ui *UI) UpdateTable(nodeData map[string]NodeData, podsByNode map[string]ma
 			SetExpansion(1))
 
 		// Status column
-		table.SetCell(i, 1, tview.NewTableCell(data.Status).
+		status := data.Status
+		if flapping.nodes[data.Name] {
+			status += flapMarker
+		}
+		table.SetCell(i, 1, tview.NewTableCell(status).
 			SetTextColor(func() tcell.Color {
 				if data.Status == NodeStatusReady {
 					return tcell.ColorGreen
@@ -638,8 +1105,11 @@ func (ui *UI) UpdateTable(nodeData map[string]NodeData, podsByNode map[string]ma
 
 		// Namespace columns with pod indicators
 		for nsIdx, namespace := range namespaces {
-			indicators := filteredPodData[data.Name][namespace]
-			cell := tview.NewTableCell(strings.Join(indicators, "")).
+			indicators := strings.Join(filteredPodData[data.Name][namespace], "")
+			if flapping.namespaces[data.Name][namespace] {
+				indicators += flapMarker
+			}
+			cell := tview.NewTableCell(indicators).
 				SetExpansion(1).
 				SetAlign(tview.AlignLeft)
 			table.SetCell(i, 5+nsIdx, cell)
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Watcher runs change detection without the TUI and prints every change
+// event to a writer, one per line
+type Watcher struct {
+	config     *Config
+	provider   K8sProvider
+	stateCache *StateCache
+	filter     changeLogFilter
+	format     string          // text or jsonl
+	nodes      map[string]bool // Nodes seen in the previous refresh
+	resources  *resourceTracker
+	out        io.Writer
+	errOut     io.Writer // Refresh errors are reported here and retried
+}
+
+// NewWatcher creates a watcher printing change events to out in the output
+// format of the config, text (default) or jsonl
+func NewWatcher(config *Config, out, errOut io.Writer) (*Watcher, error) {
+	format := config.OutputFormat
+	switch format {
+	case "":
+		format = ChangeLogFormatText
+	case ChangeLogFormatText, ChangeLogFormatJSONL:
+	default:
+		return nil, fmt.Errorf("unsupported watch format %q", format)
+	}
+	filter, err := parseChangeLogFilter(config.ChangeFilter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid change filter: %v", err)
+	}
+	provider, err := newProvider(config)
+	if err != nil {
+		return nil, err
+	}
+	stateCache := NewStateCache()
+	return &Watcher{
+		config:     config,
+		provider:   provider,
+		stateCache: stateCache,
+		filter:     filter,
+		format:     format,
+		nodes:      make(map[string]bool),
+		resources:  &resourceTracker{provider: provider, stateCache: stateCache},
+		out:        out,
+		errOut:     errOut,
+	}, nil
+}
+
+// Run loads the initial state, then refreshes every RefreshInterval and
+// prints the changes until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context) error {
+	// Like the TUI, the initial state is not reported as changes
+	nodeData, err := w.fetch()
+	if err != nil {
+		return fmt.Errorf("failed to load initial data: %v", err)
+	}
+	for nodeName, data := range nodeData {
+		w.stateCache.Put(nodeName, ResourceState{
+			Data:      data,
+			Timestamp: time.Now(),
+		})
+		w.nodes[nodeName] = true
+	}
+	if _, err := w.resources.changes(w.config.IncludeNamespaces, w.config.ExcludeNamespaces); err != nil {
+		fmt.Fprintf(w.errOut, "failed to load workloads and events: %v\n", err)
+	}
+
+	ticker := time.NewTicker(RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		nodeData, err := w.fetch()
+		if err != nil {
+			fmt.Fprintf(w.errOut, "failed to refresh data: %v\n", err)
+			continue
+		}
+		changes := w.diff(nodeData)
+		resourceChanges, err := w.resources.changes(w.config.IncludeNamespaces, w.config.ExcludeNamespaces)
+		if err != nil {
+			fmt.Fprintf(w.errOut, "failed to refresh workloads and events: %v\n", err)
+		}
+		for _, change := range append(changes, resourceChanges...) {
+			if !w.filter.Matches(change) {
+				continue
+			}
+			if err := w.print(change); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// fetch gets the current node data with the namespace and search filters applied
+func (w *Watcher) fetch() (map[string]NodeData, error) {
+	nodeData, _, err := fetchFilteredData(w.provider, w.config)
+	return nodeData, err
+}
+
+// fetchFilteredData updates the provider's data and applies the namespace
+// filters and search query of the config
+func fetchFilteredData(provider K8sProvider, config *Config) (map[string]NodeData, map[string]map[string][]string, error) {
+	nodeData, podsByNode, err := provider.UpdateNodeData(config.IncludeNamespaces, config.ExcludeNamespaces)
+	if err != nil || config.SearchQuery == "" {
+		return nodeData, podsByNode, err
+	}
+	return provider.GetFilteredData(FilterCriteria{
+		IncludeNamespaces: config.IncludeNamespaces,
+		ExcludeNamespaces: config.ExcludeNamespaces,
+		SearchQuery:       config.SearchQuery,
+	})
+}
+
+// diff returns the changes since the previous refresh, including removed nodes
+func (w *Watcher) diff(nodeData map[string]NodeData) []ChangeEvent {
+	var changes []ChangeEvent
+	for nodeName, newData := range nodeData {
+		changes = append(changes, w.stateCache.Compare(nodeName, ResourceState{
+			Data:      newData,
+			Timestamp: time.Now(),
+		})...)
+	}
+	for nodeName := range w.nodes {
+		if _, exists := nodeData[nodeName]; !exists {
+			changes = append(changes, w.stateCache.Compare(nodeName, ResourceState{
+				Data:      nil,
+				Timestamp: time.Now(),
+			})...)
+		}
+	}
+
+	w.nodes = make(map[string]bool, len(nodeData))
+	for nodeName := range nodeData {
+		w.nodes[nodeName] = true
+	}
+	return changes
+}
+
+// print writes one change event in the watcher's format
+func (w *Watcher) print(change ChangeEvent) error {
+	record := NewChangeRecord(w.provider.GetClusterName(), change)
+	if w.format == ChangeLogFormatJSONL {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w.out, "%s\n", data)
+		return err
+	}
+	_, err := fmt.Fprintln(w.out, record.text())
+	return err
+}
@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// WebClientBuffer is the number of events queued per browser. Slower
+	// browsers are disconnected and reload the full state when they reconnect.
+	WebClientBuffer = 64
+
+	// WebKeepAlive is the interval of comments keeping idle event streams open through proxies
+	WebKeepAlive = 15 * time.Second
+)
+
+// webDashboardPage is the single-page browser view
+//
+//go:embed web/index.html
+var webDashboardPage []byte
+
+// webEvent is a server-sent event
+type webEvent struct {
+	name string // state or change
+	data []byte
+}
+
+// WebDashboard serves a read-only browser version of the node matrix, pod
+// drill-down and change log, pushing updates to browsers as server-sent events
+type WebDashboard struct {
+	mu         sync.Mutex
+	state      []byte         // JSON of the latest webState
+	changes    []ChangeRecord // Newest last
+	maxChanges int
+	clients    map[chan webEvent]bool
+}
+
+// NewWebDashboard creates a web dashboard keeping up to maxChanges change events
+func NewWebDashboard(maxChanges int) *WebDashboard {
+	if maxChanges <= 0 {
+		maxChanges = DefaultChangeLogSize
+	}
+	return &WebDashboard{
+		state:      []byte("null"),
+		maxChanges: maxChanges,
+		clients:    make(map[chan webEvent]bool),
+	}
+}
+
+// webState is the part of a Snapshot the page renders. Labels, annotations,
+// allocatable resources and the like are left out, so teammates given the
+// read-only view don't see more of the cluster than the dashboard shows.
+type webState struct {
+	Cluster    string                       `json:"cluster"`
+	Time       time.Time                    `json:"time"`
+	Namespaces []string                     `json:"namespaces"`
+	Nodes      []webNode                    `json:"nodes"`
+	PodGlyphs  map[string]map[string]string `json:"podGlyphs"`
+}
+
+// webNode is a node row of the matrix with the pods for the drill-down
+type webNode struct {
+	Name          string
+	Status        string
+	Unschedulable bool
+	Version       string
+	Age           string
+	PodCount      string
+	Pods          map[string]webPod
+}
+
+// webPod is a pod of the drill-down
+type webPod struct {
+	Name          string
+	Namespace     string
+	Status        string
+	RestartCount  int
+	ContainerInfo map[string]ContainerInfo
+}
+
+// newWebState trims a snapshot to the rendered fields
+func newWebState(snapshot *Snapshot) webState {
+	state := webState{
+		Cluster:    snapshot.Cluster,
+		Time:       snapshot.Time,
+		Namespaces: snapshot.Namespaces,
+		Nodes:      make([]webNode, 0, len(snapshot.Nodes)),
+		PodGlyphs:  snapshot.PodGlyphs,
+	}
+	for _, node := range snapshot.Nodes {
+		pods := make(map[string]webPod, len(node.Pods))
+		for name, pod := range node.Pods {
+			pods[name] = webPod{
+				Name:          pod.Name,
+				Namespace:     pod.Namespace,
+				Status:        pod.Status,
+				RestartCount:  pod.RestartCount,
+				ContainerInfo: pod.ContainerInfo,
+			}
+		}
+		state.Nodes = append(state.Nodes, webNode{
+			Name:          node.Name,
+			Status:        node.Status,
+			Unschedulable: node.Unschedulable,
+			Version:       node.Version,
+			Age:           node.Age,
+			PodCount:      node.PodCount,
+			Pods:          pods,
+		})
+	}
+	return state
+}
+
+// Update replaces the shown state and pushes it to connected browsers
+func (d *WebDashboard) Update(snapshot *Snapshot) error {
+	data, err := json.Marshal(newWebState(snapshot))
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.state = data
+	d.broadcast(webEvent{name: "state", data: data})
+	return nil
+}
+
+// AddChanges appends change events to the log and pushes them to connected browsers
+func (d *WebDashboard) AddChanges(records []ChangeRecord) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			continue
+		}
+		d.changes = append(d.changes, record)
+		d.broadcast(webEvent{name: "change", data: data})
+	}
+	if excess := len(d.changes) - d.maxChanges; excess > 0 {
+		d.changes = append([]ChangeRecord(nil), d.changes[excess:]...)
+	}
+}
+
+// broadcast queues an event for every client, disconnecting those that fall behind.
+// Must be called with the lock held.
+func (d *WebDashboard) broadcast(event webEvent) {
+	for client := range d.clients {
+		select {
+		case client <- event:
+		default:
+			delete(d.clients, client)
+			close(client)
+		}
+	}
+}
+
+// Handler returns the handler serving the page, the JSON endpoints and the event stream
+func (d *WebDashboard) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", d.readOnly(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(webDashboardPage)
+	}))
+	mux.HandleFunc("/api/state", d.readOnly(func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		state := d.state
+		d.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(state)
+	}))
+	mux.HandleFunc("/api/changes", d.readOnly(func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		changes := append([]ChangeRecord{}, d.changes...)
+		d.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(changes)
+	}))
+	mux.HandleFunc("/events", d.readOnly(d.serveEvents))
+	return mux
+}
+
+// readOnly rejects requests other than GET and HEAD
+func (d *WebDashboard) readOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "read-only dashboard", http.StatusMethodNotAllowed)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// serveEvents streams the current state, the change log and then every update
+// as server-sent events until the browser disconnects
+func (d *WebDashboard) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	// Subscribe and take the current state under the same lock so no update is missed
+	client := make(chan webEvent, WebClientBuffer)
+	d.mu.Lock()
+	d.clients[client] = true
+	state := d.state
+	changes, _ := json.Marshal(d.changes)
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		if d.clients[client] {
+			delete(d.clients, client)
+			close(client)
+		}
+		d.mu.Unlock()
+	}()
+
+	writeEvent(w, webEvent{name: "state", data: state})
+	writeEvent(w, webEvent{name: "changes", data: changes})
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(WebKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event, ok := <-client:
+			if !ok {
+				return // Fell behind, the browser reconnects
+			}
+			writeEvent(w, event)
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes one server-sent event, the JSON data never contains newlines
+func writeEvent(w http.ResponseWriter, event webEvent) {
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.name, event.data)
+}
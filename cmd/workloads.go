@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Workload kinds
+const (
+	WorkloadKindDeployment  = "Deployment"
+	WorkloadKindStatefulSet = "StatefulSet"
+	WorkloadKindDaemonSet   = "DaemonSet"
+)
+
+// workloadKey returns the name of a workload in change events, e.g. shop/Deployment/web
+func workloadKey(namespace, kind, name string) string {
+	return fmt.Sprintf("%s/%s/%s", namespace, kind, name)
+}
+
+// eventKey returns the name of an event in change events, e.g. shop/web-1.17a8c
+func eventKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// namespaceShown reports whether a namespace passes the namespace filters
+func namespaceShown(namespace string, includeNamespaces, excludeNamespaces map[string]bool) bool {
+	if excludeNamespaces[namespace] {
+		return false
+	}
+	return len(includeNamespaces) == 0 || includeNamespaces[namespace]
+}
+
+// podTemplateImages returns the container images of a pod template
+func podTemplateImages(template corev1.PodTemplateSpec) []string {
+	images := make([]string, 0, len(template.Spec.Containers))
+	for _, container := range template.Spec.Containers {
+		images = append(images, container.Image)
+	}
+	return images
+}
+
+// NewDeploymentInfo returns the watched state of a Deployment
+func NewDeploymentInfo(deployment *appsv1.Deployment) WorkloadInfo {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	return WorkloadInfo{
+		Kind:            WorkloadKindDeployment,
+		Namespace:       deployment.Namespace,
+		Replicas:        replicas,
+		ReadyReplicas:   deployment.Status.ReadyReplicas,
+		UpdatedReplicas: deployment.Status.UpdatedReplicas,
+		Images:          podTemplateImages(deployment.Spec.Template),
+	}
+}
+
+// NewStatefulSetInfo returns the watched state of a StatefulSet
+func NewStatefulSetInfo(statefulSet *appsv1.StatefulSet) WorkloadInfo {
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	return WorkloadInfo{
+		Kind:            WorkloadKindStatefulSet,
+		Namespace:       statefulSet.Namespace,
+		Replicas:        replicas,
+		ReadyReplicas:   statefulSet.Status.ReadyReplicas,
+		UpdatedReplicas: statefulSet.Status.UpdatedReplicas,
+		Images:          podTemplateImages(statefulSet.Spec.Template),
+	}
+}
+
+// NewDaemonSetInfo returns the watched state of a DaemonSet
+func NewDaemonSetInfo(daemonSet *appsv1.DaemonSet) WorkloadInfo {
+	return WorkloadInfo{
+		Kind:            WorkloadKindDaemonSet,
+		Namespace:       daemonSet.Namespace,
+		Replicas:        daemonSet.Status.DesiredNumberScheduled,
+		ReadyReplicas:   daemonSet.Status.NumberReady,
+		UpdatedReplicas: daemonSet.Status.UpdatedNumberScheduled,
+		Images:          podTemplateImages(daemonSet.Spec.Template),
+	}
+}
+
+// NewEventInfo returns the watched state of an event
+func NewEventInfo(event *corev1.Event) EventInfo {
+	return EventInfo{
+		Namespace: event.Namespace,
+		Type:      event.Type,
+		Reason:    event.Reason,
+		Object:    event.InvolvedObject.Kind + "/" + event.InvolvedObject.Name,
+		Message:   event.Message,
+		Count:     event.Count,
+	}
+}
+
+// UpdateResourceData implements K8sProvider interface. Only warning events
+// are listed, normal events are too frequent to be tracked as changes.
+func (p *RealK8sDataProvider) UpdateResourceData(includeNamespaces, excludeNamespaces map[string]bool) (map[string]WorkloadInfo, map[string]EventInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), APITimeout)
+	defer cancel()
+
+	apps := p.client.Clientset.AppsV1()
+	workloads := make(map[string]WorkloadInfo)
+	deployments, err := apps.Deployments("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list deployments (timeout %v): %v", APITimeout, err)
+	}
+	for i := range deployments.Items {
+		deployment := &deployments.Items[i]
+		if namespaceShown(deployment.Namespace, includeNamespaces, excludeNamespaces) {
+			workloads[workloadKey(deployment.Namespace, WorkloadKindDeployment, deployment.Name)] = NewDeploymentInfo(deployment)
+		}
+	}
+	statefulSets, err := apps.StatefulSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list statefulsets (timeout %v): %v", APITimeout, err)
+	}
+	for i := range statefulSets.Items {
+		statefulSet := &statefulSets.Items[i]
+		if namespaceShown(statefulSet.Namespace, includeNamespaces, excludeNamespaces) {
+			workloads[workloadKey(statefulSet.Namespace, WorkloadKindStatefulSet, statefulSet.Name)] = NewStatefulSetInfo(statefulSet)
+		}
+	}
+	daemonSets, err := apps.DaemonSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list daemonsets (timeout %v): %v", APITimeout, err)
+	}
+	for i := range daemonSets.Items {
+		daemonSet := &daemonSets.Items[i]
+		if namespaceShown(daemonSet.Namespace, includeNamespaces, excludeNamespaces) {
+			workloads[workloadKey(daemonSet.Namespace, WorkloadKindDaemonSet, daemonSet.Name)] = NewDaemonSetInfo(daemonSet)
+		}
+	}
+
+	events := make(map[string]EventInfo)
+	eventList, err := p.client.Clientset.CoreV1().Events("").List(ctx, metav1.ListOptions{FieldSelector: "type=" + corev1.EventTypeWarning})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list events (timeout %v): %v", APITimeout, err)
+	}
+	for i := range eventList.Items {
+		event := &eventList.Items[i]
+		if namespaceShown(event.Namespace, includeNamespaces, excludeNamespaces) {
+			events[eventKey(event.Namespace, event.Name)] = NewEventInfo(event)
+		}
+	}
+	return workloads, events, nil
+}
+
+// resourceTracker reports the changes of workloads and events between
+// refreshes. Nothing is reported until they were loaded once, so that
+// the first successful load, e.g. after being denied the permission to list
+// them, isn't reported as added resources.
+type resourceTracker struct {
+	provider   K8sProvider
+	stateCache *StateCache
+	loaded     bool
+}
+
+// changes fetches the workloads and events and returns their changes since
+// the previous call. Removed workloads are reported, expired events are not.
+func (t *resourceTracker) changes(includeNamespaces, excludeNamespaces map[string]bool) ([]ChangeEvent, error) {
+	workloads, events, err := t.provider.UpdateResourceData(includeNamespaces, excludeNamespaces)
+	if err != nil {
+		return nil, err
+	}
+	workloadStates := make(map[string]interface{}, len(workloads))
+	for name, workload := range workloads {
+		workloadStates[name] = workload
+	}
+	eventStates := make(map[string]interface{}, len(events))
+	for name, event := range events {
+		eventStates[name] = event
+	}
+	changes := t.stateCache.CompareResources("Workload", workloadStates, true)
+	changes = append(changes, t.stateCache.CompareResources("Event", eventStates, false)...)
+	if !t.loaded {
+		t.loaded = true
+		return nil, nil
+	}
+	return changes, nil
+}
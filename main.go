@@ -1,13 +1,36 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"k8s-nodes-example/cmd"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"text/template"
+	"time"
+)
+
+// Commands run instead of the interactive dashboard
+const (
+	commandWatch    = "watch"
+	commandSnapshot = "snapshot"
 )
 
 func main() {
-	config := parseFlags()
+	command := parseCommand()
+	config := parseFlags(command)
+
+	switch command {
+	case commandWatch:
+		runWatch(config)
+		return
+	case commandSnapshot:
+		runSnapshot(config)
+		return
+	}
 
 	app, err := cmd.NewApp(config)
 	if err != nil {
@@ -19,18 +42,175 @@ func main() {
 	}
 }
 
-// parseFlags parses command line flags and returns a Config
-func parseFlags() *cmd.Config {
+// parseCommand removes a leading command such as watch from the arguments
+func parseCommand() string {
+	if len(os.Args) > 1 && (os.Args[1] == commandWatch || os.Args[1] == commandSnapshot) {
+		command := os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+		return command
+	}
+	return ""
+}
+
+// runWatch prints change events to stdout until interrupted
+func runWatch(config *cmd.Config) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	watcher, err := cmd.NewWatcher(config, os.Stdout, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := watcher.Run(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// runSnapshot prints the current state once
+func runSnapshot(config *cmd.Config) {
+	snapshot, err := cmd.TakeSnapshot(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := snapshot.Write(os.Stdout, config.OutputFormat); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// parseFlags parses command line flags for a command, empty for the dashboard, and returns a Config
+func parseFlags(command string) *cmd.Config {
 	var namespaces []string
 	var useMockData bool
 	var logFilePath string
+	var logFileFormat string
+	var logFileMaxSizeMB int64
+	var logFileRotate time.Duration
+	var logFileMaxBackups int
+	var logFileMaxAge time.Duration
+	var logFileHistory int
+	var changeLogSize int
+	var logMaxLines int
+	var logExportDir string
+	var rulesPath string
+	var webhooks []string
+	var webhookTemplate string
+	var execHooks [][]string
+	var sinkFile string
+	var sinkDeliver string
+	var metricsAddr string
+	var httpAddr string
+	var apiAddr string
+	var searchQuery string
+	var changeFilter string
+	var outputFormat string
 
 	flag.Var((*cmd.ArrayFlags)(&namespaces), "N", "Filter by namespace (can be specified multiple times or comma-separated, prefix with - to exclude)")
 	flag.Var((*cmd.ArrayFlags)(&namespaces), "namespace", "Filter by namespace (can be specified multiple times or comma-separated, prefix with - to exclude)")
 	flag.BoolVar(&useMockData, "mock-k8s-data", false, "Use mock Kubernetes data instead of real cluster")
 	flag.StringVar(&logFilePath, "logfile", "", "Path to file for logging changes")
+	flag.StringVar(&logFileFormat, "logfile-format", "", "Change log file format: text, jsonl or csv (default from file extension, else text)")
+	flag.Int64Var(&logFileMaxSizeMB, "logfile-max-size", 0, "Rotate the change log file when it reaches this size in MB (0 disables)")
+	flag.DurationVar(&logFileRotate, "logfile-rotate", 0, "Rotate the change log file at this interval, e.g. 24h (0 disables)")
+	flag.IntVar(&logFileMaxBackups, "logfile-max-backups", 0, "Number of rotated change log files to keep (0 keeps all)")
+	flag.DurationVar(&logFileMaxAge, "logfile-max-age", 0, "Delete rotated change log files older than this, e.g. 168h (0 keeps all)")
+	flag.IntVar(&logFileHistory, "logfile-history", cmd.DefaultChangeLogHistory, "Number of recent entries reloaded from a jsonl or csv change log file at startup (0 disables)")
+	flag.IntVar(&changeLogSize, "changelog-size", cmd.DefaultChangeLogSize, "Number of change events kept in the change log pane")
+	flag.IntVar(&logMaxLines, "log-max-lines", cmd.DefaultLogMaxLines, "Maximum number of lines kept in the pod log view")
+	flag.StringVar(&logExportDir, "log-export-dir", ".", "Directory for logs saved or recorded from the pod log view")
+	flag.StringVar(&rulesPath, "rules", "", "Path to a YAML or JSON alert rules file, shows the alerts pane")
+	flag.Func("webhook", "URL to post change events and alerts to as JSON (can be specified multiple times)", func(url string) error {
+		webhooks = append(webhooks, url)
+		return nil
+	})
+	flag.StringVar(&webhookTemplate, "webhook-template", "", "Template file for the webhook JSON body, or \"slack\" for Slack-compatible messages")
+	flag.Func("exec-hook", "Command run for every change event and alert with its JSON on stdin, split into arguments like a shell without expansion (can be specified multiple times)", func(command string) error {
+		args, err := cmd.SplitCommand(command)
+		if err != nil {
+			return err
+		}
+		execHooks = append(execHooks, args)
+		return nil
+	})
+	flag.StringVar(&sinkFile, "sink-file", "", "File to append change events and alerts to as JSON lines")
+	flag.StringVar(&sinkDeliver, "sink-deliver", cmd.SinkDeliverAll, "What webhooks, exec hooks and the sink file receive: all, changes or alerts")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "Address to serve Prometheus metrics on at /metrics, e.g. :9100")
+	flag.StringVar(&httpAddr, "http", "", "Address to serve a read-only web version of the dashboard on, e.g. :8080")
+	flag.StringVar(&apiAddr, "api", "", "Address of the JSON API and remote control, host:port or unix:/path/to/socket")
+	flag.StringVar(&searchQuery, "search", "", "Show only pods whose name contains this text, like the / search")
+	flag.StringVar(&changeFilter, "filter", "", "Show only change events matching this change log filter, e.g. \"type:Pod change:Modified ns:payments\"")
+	flag.StringVar(&outputFormat, "o", "", "Output format of the watch command, text (default) or jsonl, or of the snapshot command, table (default), json, csv or markdown")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [watch|snapshot] [flags]\n\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Commands:\n"+
+			"  watch\tPrint change events to stdout without the dashboard until interrupted\n"+
+			"  snapshot\tPrint the node and namespace matrix once\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	validFormats := map[string][]string{
+		"":              {""},
+		commandWatch:    {"", cmd.ChangeLogFormatText, cmd.ChangeLogFormatJSONL},
+		commandSnapshot: {"", cmd.SnapshotFormatTable, cmd.SnapshotFormatJSON, cmd.SnapshotFormatCSV, cmd.SnapshotFormatMarkdown},
+	}
+	if !contains(validFormats[command], outputFormat) {
+		if command == "" {
+			fmt.Fprintf(os.Stderr, "flag -o is only used by the watch and snapshot commands\n")
+		} else {
+			fmt.Fprintf(os.Stderr, "invalid value %q for flag -o: expected %s with the %s command\n",
+				outputFormat, strings.Join(validFormats[command][1:], ", "), command)
+		}
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch logFileFormat {
+	case "", cmd.ChangeLogFormatText, cmd.ChangeLogFormatJSONL, cmd.ChangeLogFormatCSV:
+	default:
+		fmt.Fprintf(os.Stderr, "invalid value %q for flag -logfile-format: expected text, jsonl or csv\n", logFileFormat)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch sinkDeliver {
+	case cmd.SinkDeliverAll, cmd.SinkDeliverChanges, cmd.SinkDeliverAlerts:
+	default:
+		fmt.Fprintf(os.Stderr, "invalid value %q for flag -sink-deliver: expected all, changes or alerts\n", sinkDeliver)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if apiAddr != "" && apiAddr == httpAddr {
+		// Pages served by the web dashboard would be same-origin with the API
+		fmt.Fprintf(os.Stderr, "flags -api and -http must use different addresses\n")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var webhookBody *template.Template
+	if webhookTemplate != "" {
+		var err error
+		webhookBody, err = cmd.LoadWebhookTemplate(webhookTemplate)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid webhook template: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	var alertRules []cmd.AlertRule
+	if rulesPath != "" {
+		var err error
+		alertRules, err = cmd.LoadAlertRules(rulesPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid alert rules: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
 	// Create maps for included and excluded namespaces
 	includeNamespaces := make(map[string]bool)
 	excludeNamespaces := make(map[string]bool)
@@ -48,5 +228,36 @@ func parseFlags() *cmd.Config {
 		ExcludeNamespaces: excludeNamespaces,
 		UseMockData:       useMockData,
 		LogFilePath:       logFilePath,
+		LogFileFormat:     logFileFormat,
+		LogFileMaxSize:    logFileMaxSizeMB * 1024 * 1024,
+		LogFileRotate:     logFileRotate,
+		LogFileMaxBackups: logFileMaxBackups,
+		LogFileMaxAge:     logFileMaxAge,
+		LogFileHistory:    logFileHistory,
+		ChangeLogSize:     changeLogSize,
+		LogMaxLines:       logMaxLines,
+		LogExportDir:      logExportDir,
+		AlertRules:        alertRules,
+		Webhooks:          webhooks,
+		WebhookTemplate:   webhookBody,
+		ExecHooks:         execHooks,
+		SinkFile:          sinkFile,
+		SinkDeliver:       sinkDeliver,
+		MetricsAddr:       metricsAddr,
+		HTTPAddr:          httpAddr,
+		APIAddr:           apiAddr,
+		SearchQuery:       searchQuery,
+		ChangeFilter:      changeFilter,
+		OutputFormat:      outputFormat,
+	}
+}
+
+// contains reports whether values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
 	}
+	return false
 }